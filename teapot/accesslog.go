@@ -0,0 +1,178 @@
+package teapot
+
+import (
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"gadget/logging"
+)
+
+// DefaultAccessLogBodyBytes is how much of a body is logged when
+// MaxBodyBytes is not set.
+const DefaultAccessLogBodyBytes = 1024
+
+const redacted = "REDACTED"
+
+// DefaultRedactedHeaders are never logged with their values.
+var DefaultRedactedHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie"}
+
+// DefaultRedactedQuery are query parameters whose values are never logged.
+var DefaultRedactedQuery = []string{"access_token", "api_key", "apikey", "password", "secret", "signature", "token"}
+
+// AccessLogConfig enables logging every attempt of a request with the
+// Logger of the Session, including the method, URL, status, size of the
+// body, duration, attempt number and the redirects that were followed.
+type AccessLogConfig struct {
+	// Level is either `info`, the default, or `debug`.
+	Level string `mapstructure:"level" json:"level,omitempty"`
+
+	// Headers adds the request and response headers to the log.
+	Headers bool `mapstructure:"headers" json:"headers,omitempty"`
+
+	// Body adds the beginning of replayable request bodies and of
+	// buffered response bodies to the log.
+	Body bool `mapstructure:"body" json:"body,omitempty"`
+
+	// MaxBodyBytes limits how much of a body is logged;
+	// DefaultAccessLogBodyBytes if zero.
+	MaxBodyBytes int `mapstructure:"max_body_bytes" json:"max_body_bytes,omitempty"`
+
+	// RedactHeaders are redacted in addition to DefaultRedactedHeaders.
+	RedactHeaders []string `mapstructure:"redact_headers" json:"redact_headers,omitempty"`
+
+	// RedactQuery are redacted in addition to DefaultRedactedQuery.
+	RedactQuery []string `mapstructure:"redact_query" json:"redact_query,omitempty"`
+}
+
+// record logs a single attempt; body is nil for streamed responses.
+func (cfg *AccessLogConfig) record(log logging.Logger, req *http.Request, resp *http.Response, body []byte, attempt Attempt) {
+	if cfg == nil || log == nil {
+		return
+	}
+
+	var fields = []interface{}{
+		"method", req.Method,
+		"url", cfg.redactURL(req.URL),
+		"attempt", attempt.Number,
+		"duration", attempt.Duration,
+	}
+	if resp != nil {
+		var size = resp.ContentLength
+		if body != nil {
+			size = int64(len(body))
+		}
+		fields = append(fields, "status", resp.StatusCode, "bytes", size)
+		if redirects := cfg.redirects(resp); len(redirects) != 0 {
+			fields = append(fields, "redirects", redirects)
+		}
+	}
+	if attempt.Error != nil {
+		fields = append(fields, "error", attempt.Error.Error())
+	}
+	if cfg.Headers {
+		fields = append(fields, "request_headers", cfg.redactHeader(req.Header))
+		if resp != nil {
+			fields = append(fields, "response_headers", cfg.redactHeader(resp.Header))
+		}
+	}
+	if cfg.Body {
+		if content := cfg.requestBody(req); content != "" {
+			fields = append(fields, "request_body", content)
+		}
+		if len(body) != 0 {
+			fields = append(fields, "response_body", cfg.truncate(body))
+		}
+	}
+
+	if strings.EqualFold(cfg.Level, "debug") {
+		log.Debugw("http request", fields...)
+	} else {
+		log.Infow("http request", fields...)
+	}
+}
+
+// redirects lists the locations that were redirected to, oldest first.
+func (cfg *AccessLogConfig) redirects(resp *http.Response) []string {
+	var chain []string
+	for req := resp.Request; req != nil && req.Response != nil; req = req.Response.Request {
+		chain = append([]string{cfg.redactURL(req.URL)}, chain...)
+	}
+	return chain
+}
+
+func (cfg *AccessLogConfig) redactURL(loc *url.URL) string {
+	if loc == nil {
+		return ""
+	}
+	var clone = *loc
+	var query = clone.Query()
+	var changed bool
+	for name := range query {
+		if cfg.redactsQuery(name) {
+			query.Set(name, redacted)
+			changed = true
+		}
+	}
+	if changed {
+		clone.RawQuery = query.Encode()
+	}
+	return clone.Redacted()
+}
+
+func (cfg *AccessLogConfig) redactsQuery(name string) bool {
+	for _, list := range [][]string{DefaultRedactedQuery, cfg.RedactQuery} {
+		for _, redact := range list {
+			if strings.EqualFold(name, redact) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (cfg *AccessLogConfig) redactHeader(header http.Header) map[string]string {
+	var fields = make(map[string]string, len(header))
+	for name, values := range header {
+		fields[name] = strings.Join(values, ", ")
+	}
+	for _, list := range [][]string{DefaultRedactedHeaders, cfg.RedactHeaders} {
+		for _, redact := range list {
+			var name = http.CanonicalHeaderKey(redact)
+			if _, found := fields[name]; found {
+				fields[name] = redacted
+			}
+		}
+	}
+	return fields
+}
+
+// requestBody reads the beginning of a body that can be replayed,
+// which leaves the body sent with the request untouched.
+func (cfg *AccessLogConfig) requestBody(req *http.Request) string {
+	if req.GetBody == nil {
+		return ""
+	}
+	var body, err = req.GetBody()
+	if err != nil {
+		return ""
+	}
+	defer body.Close()
+	var content, _ = io.ReadAll(io.LimitReader(body, int64(cfg.maxBodyBytes())+1))
+	return cfg.truncate(content)
+}
+
+func (cfg *AccessLogConfig) truncate(content []byte) string {
+	if len(content) > cfg.maxBodyBytes() {
+		return string(content[:cfg.maxBodyBytes()]) + "..."
+	}
+	return string(content)
+}
+
+func (cfg *AccessLogConfig) maxBodyBytes() int {
+	if cfg.MaxBodyBytes > 0 {
+		return cfg.MaxBodyBytes
+	}
+	return DefaultAccessLogBodyBytes
+}
@@ -0,0 +1,410 @@
+package teapot
+
+import (
+	"crypto/md5" // #nosec G501 -- MD5 is mandated by RFC 7616 for compatibility
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Authenticator adds credentials to requests made by a Session.
+//
+// Authenticate has the signature of a RequestInterceptor and is called
+// before every attempt of a request, including retries, so it can be
+// handed to OnRequest directly when challenges are not needed.
+type Authenticator interface {
+	Authenticate(req *http.Request) error
+}
+
+// Challenger is implemented by an Authenticator that can react to a
+// `401 Unauthorized` response, e.g. by parsing a `WWW-Authenticate`
+// challenge or discarding an expired token. When Challenge returns true
+// the request is sent once more with fresh credentials.
+type Challenger interface {
+	Challenge(resp *http.Response) (bool, error)
+}
+
+type basicAuth struct {
+	username string
+	password string
+}
+
+// BasicAuth sends static credentials with the `Basic` scheme of RFC 7617.
+func BasicAuth(username string, password string) Authenticator {
+	return &basicAuth{username: username, password: password}
+}
+
+func (auth *basicAuth) Authenticate(req *http.Request) error {
+	req.SetBasicAuth(auth.username, auth.password)
+	return nil
+}
+
+type bearerAuth struct {
+	token string
+}
+
+// BearerAuth sends a static token with the `Bearer` scheme of RFC 6750.
+func BearerAuth(token string) Authenticator {
+	return &bearerAuth{token: token}
+}
+
+func (auth *bearerAuth) Authenticate(req *http.Request) error {
+	req.Header.Set("Authorization", "Bearer "+auth.token)
+	return nil
+}
+
+// digestAuth implements the `Digest` scheme of RFC 7616.
+//
+// The first request is sent without credentials; the challenge of the
+// resulting 401 is remembered and used, with an incrementing nonce count,
+// for that request and every following one until the server issues a new
+// nonce.
+type digestAuth struct {
+	mu        sync.Mutex
+	username  string
+	password  string
+	challenge map[string]string
+	count     uint32
+}
+
+// DigestAuth sends credentials with the `Digest` scheme of RFC 7616.
+func DigestAuth(username string, password string) Authenticator {
+	return &digestAuth{username: username, password: password}
+}
+
+func (auth *digestAuth) Challenge(resp *http.Response) (bool, error) {
+	auth.mu.Lock()
+	defer auth.mu.Unlock()
+
+	for _, value := range resp.Header.Values("WWW-Authenticate") {
+		var scheme, params, _ = strings.Cut(strings.TrimSpace(value), " ")
+		if !strings.EqualFold(scheme, "Digest") {
+			continue
+		}
+
+		var challenge = parseAuthParams(params)
+		if challenge["nonce"] == "" {
+			return false, &AuthError{Scheme: "Digest", Reason: "challenge without nonce"}
+		}
+
+		// a repeated nonce that is not stale means the credentials were rejected
+		var previous = auth.challenge
+		if previous != nil && previous["nonce"] == challenge["nonce"] && !strings.EqualFold(challenge["stale"], "true") {
+			return false, nil
+		}
+
+		auth.challenge = challenge
+		auth.count = 0
+		return true, nil
+	}
+
+	return false, nil
+}
+
+func (auth *digestAuth) Authenticate(req *http.Request) error {
+	auth.mu.Lock()
+	defer auth.mu.Unlock()
+
+	if auth.challenge == nil {
+		return nil
+	}
+
+	var algorithm = strings.ToUpper(auth.challenge["algorithm"])
+	if algorithm == "" {
+		algorithm = "MD5"
+	}
+	var newHash func() hash.Hash
+	switch strings.TrimSuffix(algorithm, "-SESS") {
+	case "MD5":
+		newHash = md5.New
+	case "SHA-256":
+		newHash = sha256.New
+	default:
+		return &AuthError{Scheme: "Digest", Reason: "unsupported algorithm " + algorithm}
+	}
+	var digest = func(parts ...string) string {
+		var h = newHash()
+		_, _ = io.WriteString(h, strings.Join(parts, ":"))
+		return hex.EncodeToString(h.Sum(nil))
+	}
+
+	var err error
+	var cnonce string
+	if cnonce, err = randomHex(16); err != nil {
+		return err
+	}
+	auth.count++
+	var nc = fmt.Sprintf("%08x", auth.count)
+	var realm = auth.challenge["realm"]
+	var nonce = auth.challenge["nonce"]
+	var uri = req.URL.RequestURI()
+
+	var qop string
+	for _, offered := range strings.Split(auth.challenge["qop"], ",") {
+		offered = strings.TrimSpace(offered)
+		if offered == "auth" {
+			qop = "auth"
+			break
+		}
+		if offered == "auth-int" {
+			qop = "auth-int"
+		}
+	}
+
+	var ha1 = digest(auth.username, realm, auth.password)
+	if strings.HasSuffix(algorithm, "-SESS") {
+		ha1 = digest(ha1, nonce, cnonce)
+	}
+
+	var ha2 = digest(req.Method, uri)
+	if qop == "auth-int" {
+		var body []byte
+		if req.GetBody != nil {
+			var reader io.ReadCloser
+			if reader, err = req.GetBody(); err != nil {
+				return err
+			}
+			body, err = io.ReadAll(reader)
+			_ = reader.Close()
+			if err != nil {
+				return err
+			}
+		}
+		ha2 = digest(req.Method, uri, digest(string(body)))
+	}
+
+	var response string
+	if qop == "" {
+		response = digest(ha1, nonce, ha2)
+	} else {
+		response = digest(ha1, nonce, nc, cnonce, qop, ha2)
+	}
+
+	var header strings.Builder
+	header.WriteString(fmt.Sprintf(`Digest username=%q, realm=%q, nonce=%q, uri=%q, algorithm=%s, response=%q`,
+		auth.username, realm, nonce, uri, algorithm, response))
+	if qop != "" {
+		header.WriteString(fmt.Sprintf(`, qop=%s, nc=%s, cnonce=%q`, qop, nc, cnonce))
+	}
+	if opaque, ok := auth.challenge["opaque"]; ok {
+		header.WriteString(fmt.Sprintf(`, opaque=%q`, opaque))
+	}
+	req.Header.Set("Authorization", header.String())
+
+	return nil
+}
+
+// parseAuthParams parses the comma separated `name=value` pairs of a
+// challenge, where values may be quoted strings containing commas.
+func parseAuthParams(params string) map[string]string {
+	var parsed = make(map[string]string)
+	for params != "" {
+		var name, rest, found = strings.Cut(params, "=")
+		if !found {
+			break
+		}
+		name = strings.ToLower(strings.TrimSpace(strings.TrimLeft(name, ", ")))
+		rest = strings.TrimSpace(rest)
+
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			var escaped bool
+			var end = -1
+			for i := 1; i < len(rest); i++ {
+				if escaped {
+					escaped = false
+					continue
+				}
+				if rest[i] == '\\' {
+					escaped = true
+				} else if rest[i] == '"' {
+					end = i
+					break
+				}
+			}
+			if end < 0 {
+				value, rest = rest[1:], ""
+			} else {
+				value, rest = rest[1:end], rest[end+1:]
+			}
+			value = strings.ReplaceAll(value, `\"`, `"`)
+		} else {
+			value, rest, _ = strings.Cut(rest, ",")
+			value = strings.TrimSpace(value)
+		}
+
+		parsed[name] = value
+		params = strings.TrimLeft(rest, ", ")
+	}
+	return parsed
+}
+
+func randomHex(size int) (string, error) {
+	var buffer = make([]byte, size)
+	if _, err := rand.Read(buffer); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buffer), nil
+}
+
+// OAuth2Config describes an OAuth 2.0 token endpoint and client of RFC 6749.
+type OAuth2Config struct {
+	TokenURL     string   `mapstructure:"token_url" json:"token_url"`
+	ClientID     string   `mapstructure:"client_id" json:"client_id"`
+	ClientSecret string   `mapstructure:"client_secret" json:"client_secret,omitempty"`
+	Scopes       []string `mapstructure:"scopes" json:"scopes,omitempty"`
+
+	// RefreshToken is required for the refresh token flow and is replaced
+	// in memory whenever the server issues a new one.
+	RefreshToken string `mapstructure:"refresh_token" json:"refresh_token,omitempty"`
+
+	// Leeway is how long before its expiry a token is refreshed.
+	// If zero, a minute is used.
+	Leeway time.Duration `mapstructure:"leeway" json:"leeway,omitempty"`
+
+	// CredentialsInBody sends the client credentials as form parameters
+	// instead of using HTTP Basic authentication.
+	CredentialsInBody bool `mapstructure:"credentials_in_body" json:"credentials_in_body,omitempty"`
+}
+
+// OAuth2Token is a token response of RFC 6749 section 5.1.
+type OAuth2Token struct {
+	AccessToken  string    `json:"access_token"`
+	TokenType    string    `json:"token_type,omitempty"`
+	RefreshToken string    `json:"refresh_token,omitempty"`
+	ExpiresIn    int64     `json:"expires_in,omitempty"`
+	Expiry       time.Time `json:"-"`
+}
+
+type oauth2Auth struct {
+	mu     sync.Mutex
+	config OAuth2Config
+	grant  string
+	client *http.Client
+	token  *OAuth2Token
+}
+
+// OAuth2ClientCredentials authenticates with tokens obtained through the
+// client credentials grant. Tokens are cached and refreshed shortly before
+// they expire or when the server rejects them. A nil client uses
+// http.DefaultClient for the token endpoint.
+func OAuth2ClientCredentials(cfg OAuth2Config, client *http.Client) Authenticator {
+	return newOAuth2Auth(cfg, "client_credentials", client)
+}
+
+// OAuth2RefreshToken authenticates with tokens obtained through the refresh
+// token grant using cfg.RefreshToken, following any rotation of it.
+func OAuth2RefreshToken(cfg OAuth2Config, client *http.Client) Authenticator {
+	return newOAuth2Auth(cfg, "refresh_token", client)
+}
+
+func newOAuth2Auth(cfg OAuth2Config, grant string, client *http.Client) *oauth2Auth {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	if cfg.Leeway == 0 {
+		cfg.Leeway = time.Minute
+	}
+	return &oauth2Auth{config: cfg, grant: grant, client: client}
+}
+
+func (auth *oauth2Auth) Authenticate(req *http.Request) error {
+	auth.mu.Lock()
+	defer auth.mu.Unlock()
+
+	if auth.token == nil || (!auth.token.Expiry.IsZero() && time.Now().Add(auth.config.Leeway).After(auth.token.Expiry)) {
+		var err error
+		if auth.token, err = auth.fetch(req); err != nil {
+			return err
+		}
+	}
+
+	var tokenType = auth.token.TokenType
+	if tokenType == "" || strings.EqualFold(tokenType, "bearer") {
+		tokenType = "Bearer"
+	}
+	req.Header.Set("Authorization", tokenType+" "+auth.token.AccessToken)
+	return nil
+}
+
+// Challenge discards a token the server rejected so a new one is fetched.
+func (auth *oauth2Auth) Challenge(resp *http.Response) (bool, error) {
+	auth.mu.Lock()
+	defer auth.mu.Unlock()
+
+	if auth.token == nil {
+		return false, nil
+	}
+	auth.token = nil
+	return true, nil
+}
+
+func (auth *oauth2Auth) fetch(req *http.Request) (*OAuth2Token, error) {
+	var err error
+	var tokenReq *http.Request
+	var resp *http.Response
+	var body []byte
+
+	var form = url.Values{"grant_type": []string{auth.grant}}
+	if len(auth.config.Scopes) != 0 {
+		form.Set("scope", strings.Join(auth.config.Scopes, " "))
+	}
+	if auth.grant == "refresh_token" {
+		if auth.config.RefreshToken == "" {
+			return nil, &AuthError{Scheme: "OAuth2", Reason: "no refresh token"}
+		}
+		form.Set("refresh_token", auth.config.RefreshToken)
+	}
+	if auth.config.CredentialsInBody {
+		form.Set("client_id", auth.config.ClientID)
+		if auth.config.ClientSecret != "" {
+			form.Set("client_secret", auth.config.ClientSecret)
+		}
+	}
+
+	if tokenReq, err = http.NewRequestWithContext(req.Context(), http.MethodPost, auth.config.TokenURL, strings.NewReader(form.Encode())); err != nil {
+		return nil, err
+	}
+	tokenReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	tokenReq.Header.Set("Accept", "application/json")
+	if !auth.config.CredentialsInBody {
+		tokenReq.SetBasicAuth(url.QueryEscape(auth.config.ClientID), url.QueryEscape(auth.config.ClientSecret))
+	}
+
+	var requested = time.Now()
+	if resp, err = auth.client.Do(tokenReq); err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if body, err = readBody(resp.Body, 1<<20); err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, &AuthError{Scheme: "OAuth2", Reason: fmt.Sprintf("token endpoint returned %s: %s", resp.Status, body)}
+	}
+
+	var token = new(OAuth2Token)
+	if err = json.Unmarshal(body, token); err != nil {
+		return nil, err
+	}
+	if token.AccessToken == "" {
+		return nil, &AuthError{Scheme: "OAuth2", Reason: "token endpoint returned no access_token"}
+	}
+	if token.ExpiresIn > 0 {
+		token.Expiry = requested.Add(time.Duration(token.ExpiresIn) * time.Second)
+	}
+	if token.RefreshToken != "" {
+		auth.config.RefreshToken = token.RefreshToken
+	}
+
+	return token, nil
+}
@@ -0,0 +1,215 @@
+package teapot
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"golang.org/x/sync/errgroup"
+
+	"gadget/exec"
+)
+
+// BatchResult is the Result of the request at Index of a batch.
+type BatchResult struct {
+	Index  int
+	Result *Result
+}
+
+type batchConfig struct {
+	workers    int
+	method     string
+	inputOrder bool
+	failFast   bool
+}
+
+type BatchOption func(*batchConfig)
+
+// BatchWorkers limits the number of concurrent requests; the default
+// is exec.MaxParallelism().
+func BatchWorkers(workers int) BatchOption {
+	return func(cfg *batchConfig) {
+		if workers > 0 {
+			cfg.workers = workers
+		}
+	}
+}
+
+// BatchMethod changes the HTTP method used for every request from GET.
+func BatchMethod(method string) BatchOption {
+	return func(cfg *batchConfig) {
+		cfg.method = method
+	}
+}
+
+// BatchInputOrder delivers results in the order of the requests rather
+// than as soon as they complete.
+func BatchInputOrder() BatchOption {
+	return func(cfg *batchConfig) {
+		cfg.inputOrder = true
+	}
+}
+
+// BatchFailFast cancels the remaining requests after the first failure
+// instead of collecting the errors of every request.
+func BatchFailFast() BatchOption {
+	return func(cfg *batchConfig) {
+		cfg.failFast = true
+	}
+}
+
+// BatchRun is a batch of requests running in the background.
+type BatchRun struct {
+	results chan *BatchResult
+	done    chan struct{}
+	err     error
+}
+
+// Batch runs the requests through a pool of workers, e.g.:
+//
+//	var run = teapot.Batch(ctx, requests, teapot.BatchWorkers(8))
+//	for item := range run.Results() {
+//		handle(item.Index, item.Result)
+//	}
+//	if err := run.Wait(); err != nil {
+//		...
+//	}
+//
+// Requests that have not started yet are skipped once ctx ends or, with
+// BatchFailFast, a request fails; every other request yields a result.
+func Batch(ctx context.Context, requests []Requestor, options ...BatchOption) *BatchRun {
+	var cfg = batchConfig{workers: exec.MaxParallelism(), method: http.MethodGet}
+	for _, option := range options {
+		option(&cfg)
+	}
+
+	var run = &BatchRun{
+		// buffered for every request so that workers never wait on the caller
+		results: make(chan *BatchResult, len(requests)),
+		done:    make(chan struct{}),
+	}
+	var completed = make(chan *BatchResult, len(requests))
+	var group, groupCtx = errgroup.WithContext(ctx)
+	group.SetLimit(cfg.workers)
+
+	var groupErr error
+	go func() {
+		defer close(completed)
+		for index := range requests {
+			if groupCtx.Err() != nil {
+				break
+			}
+			var index, req = index, requests[index]
+			group.Go(func() error {
+				// the context may have ended while waiting for a free worker
+				if groupCtx.Err() != nil {
+					return nil
+				}
+				var res = req.Request(groupCtx, cfg.method)
+				completed <- &BatchResult{Index: index, Result: res}
+				if cfg.failFast && res.Error != nil {
+					return batchError(index, res)
+				}
+				return nil
+			})
+		}
+		groupErr = group.Wait()
+	}()
+
+	go func() {
+		defer close(run.done)
+		defer close(run.results)
+
+		var errs []error
+		var pending = make(map[int]*BatchResult)
+		var next, delivered int
+		for item := range completed {
+			delivered++
+			if item.Result.Error != nil {
+				errs = append(errs, batchError(item.Index, item.Result))
+			}
+			if !cfg.inputOrder {
+				run.results <- item
+				continue
+			}
+			pending[item.Index] = item
+			for ; pending[next] != nil; next++ {
+				run.results <- pending[next]
+				delete(pending, next)
+			}
+		}
+
+		// skipped requests leave gaps that would otherwise hold back the rest
+		var indexes = make([]int, 0, len(pending))
+		for index := range pending {
+			indexes = append(indexes, index)
+		}
+		sort.Ints(indexes)
+		for _, index := range indexes {
+			run.results <- pending[index]
+		}
+
+		switch {
+		case cfg.failFast && groupErr != nil:
+			run.err = groupErr
+		case len(errs) > 0:
+			run.err = errors.Join(errs...)
+		case delivered < len(requests):
+			run.err = ctx.Err()
+		}
+	}()
+
+	return run
+}
+
+// BatchTemplate builds a request for every set of params by replacing
+// `{name}` placeholders of the URL template with escaped values, e.g.
+// `https://example.com/users/{id}/repos?page={page}`; values are escaped
+// as a path segment before the `?` and as a query value after it.
+func BatchTemplate(session Session, template string, params []map[string]string) []Requestor {
+	var requests = make([]Requestor, 0, len(params))
+	for _, values := range params {
+		requests = append(requests, session.URLstring(expandTemplate(template, values)))
+	}
+	return requests
+}
+
+func expandTemplate(template string, values map[string]string) string {
+	var path, query, hasQuery = strings.Cut(template, "?")
+	var pathReplacements = make([]string, 0, len(values)*2)
+	var queryReplacements = make([]string, 0, len(values)*2)
+	for name, value := range values {
+		pathReplacements = append(pathReplacements, "{"+name+"}", url.PathEscape(value))
+		queryReplacements = append(queryReplacements, "{"+name+"}", url.QueryEscape(value))
+	}
+	path = strings.NewReplacer(pathReplacements...).Replace(path)
+	if !hasQuery {
+		return path
+	}
+	return path + "?" + strings.NewReplacer(queryReplacements...).Replace(query)
+}
+
+func batchError(index int, res *Result) error {
+	var err = &BatchError{Index: index, Err: res.Error}
+	if res.Request != nil {
+		err.Location = res.Request.URL
+	}
+	return err
+}
+
+// Results delivers the result of every request that ran; the channel is
+// closed when the batch is done.
+func (run *BatchRun) Results() <-chan *BatchResult {
+	return run.results
+}
+
+// Wait blocks until every request is done and returns the first error with
+// BatchFailFast, otherwise all errors joined; the Results do not have to be
+// consumed before.
+func (run *BatchRun) Wait() error {
+	<-run.done
+	return run.err
+}
@@ -0,0 +1,18 @@
+package teapot
+
+import (
+	"testing"
+)
+
+func TestBatchTemplate_EscapesPathAndQuery(t *testing.T) {
+	var template = "https://example.com/users/{id}/repos?page={page}&q={q}"
+	var got = expandTemplate(template, map[string]string{
+		"id":   "a/b c",
+		"page": "1&admin=true",
+		"q":    "x+y",
+	})
+	var want = "https://example.com/users/a%2Fb%20c/repos?page=1%26admin%3Dtrue&q=x%2By"
+	if got != want {
+		t.Errorf("did not get expected URL '%s' != '%s'", got, want)
+	}
+}
@@ -0,0 +1,253 @@
+package teapot
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/textproto"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// BodyEncoder produces request bodies along with their content headers.
+//
+// Open is called once for every attempt of a request, including retries
+// and redirects, and must return a fresh reader each time.
+type BodyEncoder interface {
+	ContentType() string
+
+	// ContentLength returns the size of the body or -1 if it is unknown.
+	ContentLength() int64
+
+	Open() (io.ReadCloser, error)
+}
+
+type bytesEncoder struct {
+	contentType string
+	content     []byte
+}
+
+func (enc *bytesEncoder) ContentType() string {
+	return enc.contentType
+}
+
+func (enc *bytesEncoder) ContentLength() int64 {
+	return int64(len(enc.content))
+}
+
+func (enc *bytesEncoder) Open() (io.ReadCloser, error) {
+	return io.NopCloser(bytes.NewReader(enc.content)), nil
+}
+
+// JSONBody marshals v as an `application/json` body.
+func JSONBody(v any) (BodyEncoder, error) {
+	var content, err = json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return &bytesEncoder{contentType: "application/json", content: content}, nil
+}
+
+// FormBody encodes values as an `application/x-www-form-urlencoded` body.
+func FormBody(values url.Values) BodyEncoder {
+	return &bytesEncoder{contentType: "application/x-www-form-urlencoded", content: []byte(values.Encode())}
+}
+
+// Part is a single field or file of a multipart body.
+type Part struct {
+	Name        string
+	Filename    string
+	ContentType string
+
+	// Size is the length of the content or -1 if it is unknown.
+	Size int64
+
+	// Open returns the content of the part; it is called for every attempt.
+	Open func() (io.ReadCloser, error)
+}
+
+// Field is a multipart form field with a literal value.
+func Field(name string, value string) Part {
+	return Part{
+		Name: name,
+		Size: int64(len(value)),
+		Open: func() (io.ReadCloser, error) {
+			return io.NopCloser(strings.NewReader(value)), nil
+		},
+	}
+}
+
+// File is a multipart file streamed from disk, using the file name as is.
+func File(name string, path string) Part {
+	var size int64 = -1
+	if info, err := os.Stat(path); err == nil {
+		size = info.Size()
+	}
+	return Part{
+		Name:        name,
+		Filename:    filepath.Base(path),
+		ContentType: "application/octet-stream",
+		Size:        size,
+		Open: func() (io.ReadCloser, error) {
+			return os.Open(filepath.Clean(path))
+		},
+	}
+}
+
+// FileReader is a multipart file whose content is produced by open;
+// size may be -1 if it is not known in advance.
+func FileReader(name string, filename string, size int64, open func() (io.ReadCloser, error)) Part {
+	return Part{
+		Name:        name,
+		Filename:    filename,
+		ContentType: "application/octet-stream",
+		Size:        size,
+		Open:        open,
+	}
+}
+
+func (part *Part) header() textproto.MIMEHeader {
+	var header = make(textproto.MIMEHeader)
+	var disposition = `form-data; name="` + escapeQuotes(part.Name) + `"`
+	if part.Filename != "" {
+		disposition += `; filename="` + escapeQuotes(part.Filename) + `"`
+	}
+	header.Set("Content-Disposition", disposition)
+	if part.ContentType != "" {
+		header.Set("Content-Type", part.ContentType)
+	}
+	return header
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+func escapeQuotes(s string) string {
+	return quoteEscaper.Replace(s)
+}
+
+// multipartEncoder streams a `multipart/form-data` body without buffering
+// it; every part is opened and copied only while the body is being read.
+type multipartEncoder struct {
+	boundary string
+	parts    []Part
+	length   int64
+}
+
+// MultipartBody encodes parts as a `multipart/form-data` body.
+func MultipartBody(parts ...Part) (BodyEncoder, error) {
+	var buffer = make([]byte, 30)
+	if _, err := rand.Read(buffer); err != nil {
+		return nil, err
+	}
+	var enc = &multipartEncoder{boundary: hex.EncodeToString(buffer), parts: parts}
+	enc.length = enc.measure()
+	return enc, nil
+}
+
+func (enc *multipartEncoder) ContentType() string {
+	return "multipart/form-data; boundary=" + enc.boundary
+}
+
+func (enc *multipartEncoder) ContentLength() int64 {
+	return enc.length
+}
+
+// measure calculates the size of the body by encoding it without any
+// content, which only works when the size of every part is known.
+func (enc *multipartEncoder) measure() int64 {
+	var total int64
+	var counter = &countingWriter{}
+	var writer = multipart.NewWriter(counter)
+	_ = writer.SetBoundary(enc.boundary)
+	for index := range enc.parts {
+		if enc.parts[index].Size < 0 {
+			return -1
+		}
+		total += enc.parts[index].Size
+		if _, err := writer.CreatePart(enc.parts[index].header()); err != nil {
+			return -1
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return -1
+	}
+	return total + counter.count
+}
+
+func (enc *multipartEncoder) Open() (io.ReadCloser, error) {
+	return &multipartReader{enc: enc}, nil
+}
+
+func (enc *multipartEncoder) write(w io.Writer) error {
+	var writer = multipart.NewWriter(w)
+	if err := writer.SetBoundary(enc.boundary); err != nil {
+		return err
+	}
+	for index := range enc.parts {
+		var err error
+		var part io.Writer
+		var content io.ReadCloser
+
+		if part, err = writer.CreatePart(enc.parts[index].header()); err != nil {
+			return err
+		}
+		if content, err = enc.parts[index].Open(); err != nil {
+			return err
+		}
+		_, err = io.Copy(part, content)
+		_ = content.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return writer.Close()
+}
+
+// multipartReader starts encoding on the first Read so that a body that
+// is never read does not leave a goroutine blocked on the pipe.
+type multipartReader struct {
+	enc    *multipartEncoder
+	once   sync.Once
+	reader *io.PipeReader
+}
+
+func (mr *multipartReader) start() {
+	var reader, writer = io.Pipe()
+	mr.reader = reader
+	go func() {
+		writer.CloseWithError(mr.enc.write(writer))
+	}()
+}
+
+func (mr *multipartReader) Read(p []byte) (int, error) {
+	mr.once.Do(mr.start)
+	// the reader was closed before it was started
+	if mr.reader == nil {
+		return 0, io.ErrClosedPipe
+	}
+	return mr.reader.Read(p)
+}
+
+func (mr *multipartReader) Close() error {
+	var started = true
+	mr.once.Do(func() { started = false })
+	if !started || mr.reader == nil {
+		return nil
+	}
+	return mr.reader.Close()
+}
+
+type countingWriter struct {
+	count int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	cw.count += int64(len(p))
+	return len(p), nil
+}
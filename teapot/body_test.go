@@ -0,0 +1,44 @@
+package teapot
+
+import (
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestMultipartBody_ReadAfterClose(t *testing.T) {
+	var enc, err = MultipartBody(Field("name", "value"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var body io.ReadCloser
+	if body, err = enc.Open(); err != nil {
+		t.Fatal(err)
+	}
+	if err = body.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = body.Read(make([]byte, 16)); !errors.Is(err, io.ErrClosedPipe) {
+		t.Errorf("did not get expected error: %v", err)
+	}
+}
+
+func TestMultipartBody_LengthMatchesContent(t *testing.T) {
+	var enc, err = MultipartBody(Field("name", "value"), Field("other", "content"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var body io.ReadCloser
+	if body, err = enc.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer body.Close()
+
+	var content []byte
+	if content, err = io.ReadAll(body); err != nil {
+		t.Fatal(err)
+	}
+	if int64(len(content)) != enc.ContentLength() {
+		t.Errorf("did not get expected length %d != %d", len(content), enc.ContentLength())
+	}
+}
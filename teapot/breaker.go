@@ -0,0 +1,248 @@
+package teapot
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"gadget/logging"
+)
+
+// DefaultBreakerStatuses are the response statuses that count as failures.
+var DefaultBreakerStatuses = []int{
+	http.StatusInternalServerError,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// CircuitBreakerConfig stops sending requests to a host that keeps failing.
+//
+// Every host has its own circuit. It opens after FailureThreshold
+// consecutive failures and then fails requests immediately with a
+// CircuitOpenError. Once the cooldown has passed the circuit is half-open
+// and lets a few trial requests through: if they succeed it closes again,
+// otherwise it reopens with twice the cooldown, up to MaxCooldown.
+type CircuitBreakerConfig struct {
+	// FailureThreshold is the number of consecutive failures that open the
+	// circuit; 5 if zero.
+	FailureThreshold int `mapstructure:"failure_threshold" json:"failure_threshold,omitempty"`
+
+	// Statuses count as failures in addition to transport errors;
+	// DefaultBreakerStatuses if empty.
+	Statuses []int `mapstructure:"statuses" json:"statuses,omitempty"`
+
+	// Cooldown is how long the circuit stays open at first; 30 seconds if zero.
+	Cooldown time.Duration `mapstructure:"cooldown" json:"cooldown,omitempty"`
+
+	// MaxCooldown caps the growing cooldown; it does not grow if zero.
+	MaxCooldown time.Duration `mapstructure:"max_cooldown" json:"max_cooldown,omitempty"`
+
+	// HalfOpenRequests is the number of trial requests that must succeed
+	// to close the circuit; 1 if zero.
+	HalfOpenRequests int `mapstructure:"half_open_requests" json:"half_open_requests,omitempty"`
+}
+
+func (cfg *CircuitBreakerConfig) threshold() int {
+	if cfg.FailureThreshold > 0 {
+		return cfg.FailureThreshold
+	}
+	return 5
+}
+
+func (cfg *CircuitBreakerConfig) cooldown() time.Duration {
+	if cfg.Cooldown > 0 {
+		return cfg.Cooldown
+	}
+	return 30 * time.Second
+}
+
+func (cfg *CircuitBreakerConfig) trials() int {
+	if cfg.HalfOpenRequests > 0 {
+		return cfg.HalfOpenRequests
+	}
+	return 1
+}
+
+func (cfg *CircuitBreakerConfig) failed(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	var statuses = cfg.Statuses
+	if len(statuses) == 0 {
+		statuses = DefaultBreakerStatuses
+	}
+	for _, status := range statuses {
+		if resp.StatusCode == status {
+			return true
+		}
+	}
+	return false
+}
+
+// CircuitState is the state of the circuit of a host.
+type CircuitState int
+
+const (
+	CircuitClosed CircuitState = iota
+	CircuitOpen
+	CircuitHalfOpen
+)
+
+func (state CircuitState) String() string {
+	switch state {
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+type circuit struct {
+	state     CircuitState
+	failures  int
+	successes int
+	trials    int
+	cooldown  time.Duration
+	until     time.Time
+}
+
+// circuitBreaker holds the circuits of a Teapot and every Session or
+// clone made from it.
+type circuitBreaker struct {
+	cfg *CircuitBreakerConfig
+	log logging.Logger
+
+	mu       sync.Mutex
+	circuits map[string]*circuit
+}
+
+func newCircuitBreaker(cfg *CircuitBreakerConfig, log logging.Logger) *circuitBreaker {
+	return &circuitBreaker{cfg: cfg, log: log, circuits: make(map[string]*circuit)}
+}
+
+// allow reports whether a request to the host may be sent; trial is true
+// for a request sent while the circuit is half-open.
+func (breaker *circuitBreaker) allow(host string) (trial bool, err error) {
+	breaker.mu.Lock()
+	defer breaker.mu.Unlock()
+
+	var c = breaker.circuits[host]
+	if c == nil {
+		c = &circuit{cooldown: breaker.cfg.cooldown()}
+		breaker.circuits[host] = c
+	}
+
+	if c.state == CircuitOpen {
+		if time.Now().Before(c.until) {
+			return false, &CircuitOpenError{Host: host, Until: c.until}
+		}
+		breaker.transition(host, c, CircuitHalfOpen)
+		c.successes, c.trials = 0, 0
+	}
+	if c.state == CircuitHalfOpen {
+		if c.trials >= breaker.cfg.trials() {
+			return false, &CircuitOpenError{Host: host, Until: c.until}
+		}
+		c.trials++
+		return true, nil
+	}
+	return false, nil
+}
+
+// record updates the circuit of a host with the outcome of a request.
+func (breaker *circuitBreaker) record(host string, trial bool, failed bool) {
+	breaker.mu.Lock()
+	defer breaker.mu.Unlock()
+
+	var c = breaker.circuits[host]
+	switch {
+	case c.state == CircuitHalfOpen && trial && failed:
+		c.cooldown *= 2
+		if breaker.cfg.MaxCooldown <= 0 {
+			c.cooldown = breaker.cfg.cooldown()
+		} else if c.cooldown > breaker.cfg.MaxCooldown {
+			c.cooldown = breaker.cfg.MaxCooldown
+		}
+		breaker.open(host, c)
+	case c.state == CircuitHalfOpen && trial:
+		c.successes++
+		if c.successes >= breaker.cfg.trials() {
+			c.failures, c.cooldown = 0, breaker.cfg.cooldown()
+			breaker.transition(host, c, CircuitClosed)
+		}
+	case c.state == CircuitClosed && failed:
+		c.failures++
+		if c.failures >= breaker.cfg.threshold() {
+			breaker.open(host, c)
+		}
+	case c.state == CircuitClosed:
+		c.failures = 0
+	}
+}
+
+func (breaker *circuitBreaker) open(host string, c *circuit) {
+	c.until = time.Now().Add(c.cooldown)
+	breaker.transition(host, c, CircuitOpen)
+}
+
+func (breaker *circuitBreaker) transition(host string, c *circuit, state CircuitState) {
+	var previous = c.state
+	c.state = state
+	if breaker.log == nil {
+		return
+	}
+	var fields = []interface{}{"host", host, "from", previous.String(), "to", state.String()}
+	if state == CircuitOpen {
+		breaker.log.Warnw("circuit breaker opened", append(fields, "cooldown", c.cooldown, "failures", c.failures)...)
+	} else {
+		breaker.log.Infow("circuit breaker state changed", fields...)
+	}
+}
+
+func (breaker *circuitBreaker) wrap(next http.RoundTripper) http.RoundTripper {
+	if breaker == nil {
+		return next
+	}
+	return &breakerTransport{breaker: breaker, next: next}
+}
+
+type breakerTransport struct {
+	breaker *circuitBreaker
+	next    http.RoundTripper
+}
+
+func (bt *breakerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var host = req.URL.Host
+	var trial, err = bt.breaker.allow(host)
+	if err != nil {
+		if req.Body != nil {
+			_ = req.Body.Close()
+		}
+		return nil, err
+	}
+
+	var resp *http.Response
+	resp, err = bt.next.RoundTrip(req)
+	if err != nil && req.Context().Err() != nil {
+		// a cancelled request says nothing about the health of the host
+		bt.breaker.release(host, trial)
+		return resp, err
+	}
+	bt.breaker.record(host, trial, bt.breaker.cfg.failed(resp, err))
+	return resp, err
+}
+
+// release gives back a trial slot without recording an outcome.
+func (breaker *circuitBreaker) release(host string, trial bool) {
+	if !trial {
+		return
+	}
+	breaker.mu.Lock()
+	defer breaker.mu.Unlock()
+	if c := breaker.circuits[host]; c != nil && c.state == CircuitHalfOpen && c.trials > 0 {
+		c.trials--
+	}
+}
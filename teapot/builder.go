@@ -10,13 +10,25 @@ import (
 type Constructor interface {
 	Logger(log logging.Logger) Constructor
 	Config(cfg *Config) Constructor
+	Retry(policy *RetryConfig) Constructor
+	RateLimits(limits ...*HostLimit) Constructor
+	Proxy(cfg *ProxyConfig) Constructor
+	AccessLog(cfg *AccessLogConfig) Constructor
+	CircuitBreaker(cfg *CircuitBreakerConfig) Constructor
+	Cache(store CacheStore) Constructor
+	Auth(auth Authenticator) Constructor
 	Transport(transport *http.Transport) Constructor
+	WrapTransport(wrappers ...TransportWrapper) Constructor
 	TLS(tlsconfig *tls.Config) Constructor
 	AddHeaders(headers http.Header) Constructor
 	SetHeaders(headers http.Header) Constructor
 	CookieJar(jar http.CookieJar) Constructor
 	OnRequest(handlers ...RequestInterceptor) Constructor
 	OnResponse(handlers ...ResponseInterceptor) Constructor
+	Use(middlewares ...Middleware) Constructor
+	UseBefore(name string, middleware Middleware) Constructor
+	UseAfter(name string, middleware Middleware) Constructor
+	Without(names ...string) Constructor
 	Apply() Teapot
 	Make() Teapot
 	New() Teapot
@@ -45,11 +57,52 @@ func (bldr *builder) Config(cfg *Config) Constructor {
 	return bldr
 }
 
+func (bldr *builder) Retry(policy *RetryConfig) Constructor {
+	bldr.opts = append(bldr.opts, UseRetry(policy))
+	return bldr
+}
+
+func (bldr *builder) RateLimits(limits ...*HostLimit) Constructor {
+	bldr.opts = append(bldr.opts, UseRateLimits(limits...))
+	return bldr
+}
+
+func (bldr *builder) CircuitBreaker(cfg *CircuitBreakerConfig) Constructor {
+	bldr.opts = append(bldr.opts, UseCircuitBreaker(cfg))
+	return bldr
+}
+
+func (bldr *builder) AccessLog(cfg *AccessLogConfig) Constructor {
+	bldr.opts = append(bldr.opts, UseAccessLog(cfg))
+	return bldr
+}
+
+func (bldr *builder) Proxy(cfg *ProxyConfig) Constructor {
+	bldr.opts = append(bldr.opts, UseProxy(cfg))
+	return bldr
+}
+
+func (bldr *builder) Cache(store CacheStore) Constructor {
+	bldr.opts = append(bldr.opts, UseCache(store))
+	return bldr
+}
+
+func (bldr *builder) Auth(auth Authenticator) Constructor {
+	bldr.opts = append(bldr.opts, UseAuth(auth))
+	return bldr
+}
+
 func (bldr *builder) Transport(transport *http.Transport) Constructor {
 	bldr.opts = append(bldr.opts, UseTransport(transport))
 	return bldr
 }
 
+func (bldr *builder) WrapTransport(wrappers ...TransportWrapper) Constructor {
+	// NOTE: WrapTransport will ADD wrappers to what already exists!
+	bldr.opts = append(bldr.opts, UseTransportWrappers(wrappers))
+	return bldr
+}
+
 func (bldr *builder) TLS(tlsconfig *tls.Config) Constructor {
 	bldr.opts = append(bldr.opts, UseTLS(tlsconfig))
 	return bldr
@@ -87,6 +140,26 @@ func (bldr *builder) OnResponse(handlers ...ResponseInterceptor) Constructor {
 	return bldr
 }
 
+func (bldr *builder) Use(middlewares ...Middleware) Constructor {
+	bldr.opts = append(bldr.opts, UseMiddleware(middlewares...))
+	return bldr
+}
+
+func (bldr *builder) UseBefore(name string, middleware Middleware) Constructor {
+	bldr.opts = append(bldr.opts, UseMiddlewareBefore(name, middleware))
+	return bldr
+}
+
+func (bldr *builder) UseAfter(name string, middleware Middleware) Constructor {
+	bldr.opts = append(bldr.opts, UseMiddlewareAfter(name, middleware))
+	return bldr
+}
+
+func (bldr *builder) Without(names ...string) Constructor {
+	bldr.opts = append(bldr.opts, RemoveMiddleware(names...))
+	return bldr
+}
+
 // Apply will modify an existing teapot and return the pointer.
 func (bldr *builder) Apply() Teapot {
 	var tpt *teapot
@@ -0,0 +1,450 @@
+package teapot
+
+import (
+	"bytes"
+	"encoding/gob"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CacheStatusHeader is added to every response that passed through the
+// cache with one of the CacheHit, CacheMiss or CacheRevalidated values.
+const CacheStatusHeader = "X-Teapot-Cache"
+
+const (
+	CacheHit         = "HIT"
+	CacheMiss        = "MISS"
+	CacheRevalidated = "REVALIDATED"
+)
+
+// maxCacheableBody prevents large downloads from being buffered in memory
+// only to be stored; responses with larger bodies are simply not cached.
+const maxCacheableBody = 16 << 20
+
+// conditionalHeaders are the request headers that make the server answer
+// differently from a stored full response, such as with a part of it or
+// a `304 Not Modified` the caller is waiting for.
+var conditionalHeaders = []string{"Range", "If-Range", "If-Match", "If-None-Match", "If-Modified-Since", "If-Unmodified-Since"}
+
+// heuristicallyCacheable lists the status codes that may be cached
+// without explicit freshness information per RFC 9110 section 15.1.
+var heuristicallyCacheable = map[int]bool{
+	http.StatusOK:                   true,
+	http.StatusNonAuthoritativeInfo: true,
+	http.StatusNoContent:            true,
+	http.StatusMultipleChoices:      true,
+	http.StatusMovedPermanently:     true,
+	http.StatusPermanentRedirect:    true,
+	http.StatusNotFound:             true,
+	http.StatusMethodNotAllowed:     true,
+	http.StatusGone:                 true,
+	http.StatusRequestURITooLong:    true,
+	http.StatusNotImplemented:       true,
+}
+
+// CacheStore persists encoded cache entries by key.
+type CacheStore interface {
+	Get(key string) ([]byte, bool)
+	Set(key string, value []byte) error
+	Delete(key string) error
+}
+
+// cacheEntry is a stored response along with what is needed to
+// calculate its age and match it against later requests.
+type cacheEntry struct {
+	StatusCode   int
+	Status       string
+	Proto        string
+	ProtoMajor   int
+	ProtoMinor   int
+	Header       http.Header
+	Body         []byte
+	Vary         http.Header
+	RequestTime  time.Time
+	ResponseTime time.Time
+}
+
+func decodeCacheEntry(content []byte) (*cacheEntry, error) {
+	var entry = new(cacheEntry)
+	if err := gob.NewDecoder(bytes.NewReader(content)).Decode(entry); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+func (entry *cacheEntry) encode() ([]byte, error) {
+	var buffer bytes.Buffer
+	if err := gob.NewEncoder(&buffer).Encode(entry); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// response builds a new http.Response for req from the stored entry.
+func (entry *cacheEntry) response(req *http.Request, now time.Time, status string) *http.Response {
+	var header = entry.Header.Clone()
+	header.Set("Age", strconv.FormatInt(int64(entry.age(now)/time.Second), 10))
+	header.Set(CacheStatusHeader, status)
+
+	return &http.Response{
+		Status:        entry.Status,
+		StatusCode:    entry.StatusCode,
+		Proto:         entry.Proto,
+		ProtoMajor:    entry.ProtoMajor,
+		ProtoMinor:    entry.ProtoMinor,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(entry.Body)),
+		ContentLength: int64(len(entry.Body)),
+		Request:       req,
+	}
+}
+
+// matches checks the request headers nominated by the stored `Vary` header.
+func (entry *cacheEntry) matches(req *http.Request) bool {
+	for name, values := range entry.Vary {
+		if name == "*" {
+			return false
+		}
+		if strings.Join(req.Header.Values(name), ",") != strings.Join(values, ",") {
+			return false
+		}
+	}
+	return true
+}
+
+func (entry *cacheEntry) date() time.Time {
+	if date, err := http.ParseTime(entry.Header.Get("Date")); err == nil {
+		return date
+	}
+	return entry.ResponseTime
+}
+
+// lifetime is the freshness lifetime per RFC 9111 section 4.2.1.
+func (entry *cacheEntry) lifetime() time.Duration {
+	var cc = parseCacheControl(entry.Header)
+
+	if maxAge, ok := cc.seconds("max-age"); ok {
+		return maxAge
+	}
+	if value := entry.Header.Get("Expires"); value != "" {
+		var expires, err = http.ParseTime(value)
+		if err != nil {
+			// invalid values such as "0" represent a time in the past
+			return 0
+		}
+		return expires.Sub(entry.date())
+	}
+	if modified, err := http.ParseTime(entry.Header.Get("Last-Modified")); err == nil && heuristicallyCacheable[entry.StatusCode] {
+		var heuristic = entry.date().Sub(modified) / 10
+		if heuristic > time.Hour*24 {
+			heuristic = time.Hour * 24
+		}
+		return heuristic
+	}
+	return 0
+}
+
+// age is the current age per RFC 9111 section 4.2.3.
+func (entry *cacheEntry) age(now time.Time) time.Duration {
+	var apparent = entry.ResponseTime.Sub(entry.date())
+	if apparent < 0 {
+		apparent = 0
+	}
+	var corrected = entry.ResponseTime.Sub(entry.RequestTime)
+	if seconds, err := strconv.ParseInt(entry.Header.Get("Age"), 10, 64); err == nil && seconds > 0 {
+		corrected += time.Duration(seconds) * time.Second
+	}
+	if corrected > apparent {
+		apparent = corrected
+	}
+	return apparent + now.Sub(entry.ResponseTime)
+}
+
+// fresh determines whether the entry can be used without contacting the
+// server, taking the request directives of RFC 9111 section 5.2.1 into account.
+func (entry *cacheEntry) fresh(reqcc cacheControl, now time.Time) bool {
+	var respcc = parseCacheControl(entry.Header)
+	if reqcc.has("no-cache") || respcc.has("no-cache") || entry.Header.Get("Pragma") == "no-cache" {
+		return false
+	}
+
+	var lifetime = entry.lifetime()
+	var age = entry.age(now)
+	if maxAge, ok := reqcc.seconds("max-age"); ok && maxAge < lifetime {
+		lifetime = maxAge
+	}
+	if minFresh, ok := reqcc.seconds("min-fresh"); ok {
+		age += minFresh
+	}
+	if age < lifetime {
+		return true
+	}
+
+	if respcc.has("must-revalidate") || !reqcc.has("max-stale") {
+		return false
+	}
+	if maxStale, ok := reqcc.seconds("max-stale"); ok {
+		return age-lifetime <= maxStale
+	}
+	// max-stale without a value accepts a response of any staleness
+	return true
+}
+
+// update refreshes the stored headers from a `304 Not Modified` response.
+func (entry *cacheEntry) update(header http.Header) {
+	for name, values := range header {
+		switch name {
+		case "Content-Length", "Content-Encoding", "Content-Range", "Transfer-Encoding", "Set-Cookie":
+			continue
+		}
+		entry.Header[name] = values
+	}
+}
+
+// cacheControl holds the lowercased directives of `Cache-Control` headers.
+type cacheControl map[string]string
+
+func parseCacheControl(header http.Header) cacheControl {
+	var cc = make(cacheControl)
+	for _, value := range header.Values("Cache-Control") {
+		for _, directive := range strings.Split(value, ",") {
+			var name, arg, _ = strings.Cut(strings.TrimSpace(directive), "=")
+			if name == "" {
+				continue
+			}
+			cc[strings.ToLower(name)] = strings.Trim(arg, `"`)
+		}
+	}
+	return cc
+}
+
+func (cc cacheControl) has(directive string) bool {
+	var _, ok = cc[directive]
+	return ok
+}
+
+func (cc cacheControl) seconds(directive string) (time.Duration, bool) {
+	var value, ok = cc[directive]
+	if !ok || value == "" {
+		return 0, false
+	}
+	var seconds, err = strconv.ParseInt(value, 10, 64)
+	if err != nil || seconds < 0 {
+		return 0, false
+	}
+	return time.Duration(seconds) * time.Second, true
+}
+
+// cacheTransport is a private HTTP cache per RFC 9111 in front of another RoundTripper.
+//
+// Only GET responses are stored; successful requests with unsafe methods
+// invalidate the stored response for their URL. Ranges and conditions set
+// by the caller go to the server untouched, and cookies are never stored
+// so they are not set again by every hit.
+type cacheTransport struct {
+	next  http.RoundTripper
+	store CacheStore
+	now   func() time.Time
+}
+
+func newCacheTransport(store CacheStore, next http.RoundTripper) http.RoundTripper {
+	if store == nil {
+		return next
+	}
+	return &cacheTransport{next: next, store: store, now: time.Now}
+}
+
+func cacheKey(req *http.Request) string {
+	return req.URL.String()
+}
+
+func (transport *cacheTransport) load(key string, req *http.Request) *cacheEntry {
+	var content, ok = transport.store.Get(key)
+	if !ok {
+		return nil
+	}
+	var entry, err = decodeCacheEntry(content)
+	if err != nil {
+		_ = transport.store.Delete(key)
+		return nil
+	}
+	if !entry.matches(req) {
+		return nil
+	}
+	return entry
+}
+
+func (transport *cacheTransport) save(key string, entry *cacheEntry) {
+	if content, err := entry.encode(); err == nil {
+		_ = transport.store.Set(key, content)
+	}
+}
+
+func (transport *cacheTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var key = cacheKey(req)
+
+	switch req.Method {
+	case http.MethodGet:
+	case http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return transport.next.RoundTrip(req)
+	default:
+		var resp, err = transport.next.RoundTrip(req)
+		if err == nil && resp.StatusCode < 400 {
+			_ = transport.store.Delete(key)
+		}
+		return resp, err
+	}
+
+	for _, name := range conditionalHeaders {
+		if req.Header.Get(name) != "" {
+			return transport.next.RoundTrip(req)
+		}
+	}
+
+	var reqcc = parseCacheControl(req.Header)
+	if reqcc.has("no-store") {
+		return transport.next.RoundTrip(req)
+	}
+
+	var entry = transport.load(key, req)
+	if entry != nil && entry.fresh(reqcc, transport.now()) {
+		return entry.response(req, transport.now(), CacheHit), nil
+	}
+	if reqcc.has("only-if-cached") {
+		return &http.Response{
+			Status:     "504 Gateway Timeout",
+			StatusCode: http.StatusGatewayTimeout,
+			Proto:      "HTTP/1.1",
+			ProtoMajor: 1,
+			ProtoMinor: 1,
+			Header:     http.Header{CacheStatusHeader: []string{CacheMiss}},
+			Body:       http.NoBody,
+			Request:    req,
+		}, nil
+	}
+
+	var outgoing = req
+	if entry != nil {
+		var etag = entry.Header.Get("ETag")
+		var modified = entry.Header.Get("Last-Modified")
+		if etag != "" || modified != "" {
+			outgoing = req.Clone(req.Context())
+			if etag != "" {
+				outgoing.Header.Set("If-None-Match", etag)
+			}
+			if modified != "" {
+				outgoing.Header.Set("If-Modified-Since", modified)
+			}
+		}
+	}
+
+	var requested = transport.now()
+	var resp, err = transport.next.RoundTrip(outgoing)
+	if err != nil {
+		return nil, err
+	}
+	var responded = transport.now()
+
+	if outgoing != req && resp.StatusCode == http.StatusNotModified {
+		discardBody(resp.Body)
+		entry.update(resp.Header)
+		entry.RequestTime = requested
+		entry.ResponseTime = responded
+		transport.save(key, entry)
+		return entry.response(req, responded, CacheRevalidated), nil
+	}
+
+	resp.Header.Set(CacheStatusHeader, CacheMiss)
+	if !storable(req, reqcc, resp) {
+		return resp, nil
+	}
+
+	entry = &cacheEntry{
+		StatusCode:   resp.StatusCode,
+		Status:       resp.Status,
+		Proto:        resp.Proto,
+		ProtoMajor:   resp.ProtoMajor,
+		ProtoMinor:   resp.ProtoMinor,
+		Header:       resp.Header.Clone(),
+		Vary:         make(http.Header),
+		RequestTime:  requested,
+		ResponseTime: responded,
+	}
+	entry.Header.Del(CacheStatusHeader)
+	entry.Header.Del("Set-Cookie")
+	for _, value := range resp.Header.Values("Vary") {
+		for _, name := range strings.Split(value, ",") {
+			name = http.CanonicalHeaderKey(strings.TrimSpace(name))
+			if name != "" {
+				entry.Vary[name] = req.Header.Values(name)
+			}
+		}
+	}
+	resp.Body = &cachingBody{ReadCloser: resp.Body, store: func(body []byte) {
+		entry.Body = body
+		transport.save(key, entry)
+	}}
+
+	return resp, nil
+}
+
+// storable decides whether a response to a GET request may be stored
+// per RFC 9111 section 3, acting as a private cache.
+func storable(req *http.Request, reqcc cacheControl, resp *http.Response) bool {
+	if !heuristicallyCacheable[resp.StatusCode] {
+		return false
+	}
+	if resp.ContentLength > maxCacheableBody {
+		return false
+	}
+	for _, value := range resp.Header.Values("Vary") {
+		if strings.TrimSpace(value) == "*" {
+			return false
+		}
+	}
+
+	var respcc = parseCacheControl(resp.Header)
+	if reqcc.has("no-store") || respcc.has("no-store") {
+		return false
+	}
+	// the key holds no credentials, so responses to authenticated requests
+	// are only shared when the server allows it, per RFC 9111 section 3.5
+	if req.Header.Get("Authorization") != "" &&
+		!respcc.has("public") && !respcc.has("s-maxage") && !respcc.has("must-revalidate") {
+		return false
+	}
+	return respcc.has("max-age") ||
+		respcc.has("no-cache") ||
+		resp.Header.Get("Expires") != "" ||
+		resp.Header.Get("ETag") != "" ||
+		resp.Header.Get("Last-Modified") != ""
+}
+
+// cachingBody copies the body as it is read and stores it once it has
+// been read completely; a body that is closed early is not stored.
+type cachingBody struct {
+	io.ReadCloser
+	buffer   bytes.Buffer
+	overflow bool
+	store    func(body []byte)
+}
+
+func (body *cachingBody) Read(p []byte) (int, error) {
+	var n, err = body.ReadCloser.Read(p)
+	if n > 0 && !body.overflow {
+		if body.buffer.Len()+n > maxCacheableBody {
+			body.overflow = true
+			body.buffer = bytes.Buffer{}
+		} else {
+			body.buffer.Write(p[:n])
+		}
+	}
+	if err == io.EOF && !body.overflow && body.store != nil {
+		body.store(body.buffer.Bytes())
+		body.store = nil
+	}
+	return n, err
+}
@@ -0,0 +1,119 @@
+package teapot
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+var lastModified = time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+func newCachedServer(t *testing.T) (*httptest.Server, *http.Client, *int) {
+	var requests int
+	var server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc"})
+		w.Header().Set("Cache-Control", "max-age=60")
+		http.ServeContent(w, r, "", lastModified, strings.NewReader("0123456789"))
+	}))
+	t.Cleanup(server.Close)
+	var client = &http.Client{Transport: newCacheTransport(NewMemoryCache(0), http.DefaultTransport)}
+	return server, client, &requests
+}
+
+func fetch(t *testing.T, client *http.Client, req *http.Request) (*http.Response, string) {
+	var resp, err = client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var body, _ = io.ReadAll(resp.Body)
+	return resp, string(body)
+}
+
+func TestCache_BypassesRangeRequests(t *testing.T) {
+	var server, client, requests = newCachedServer(t)
+
+	var req, _ = http.NewRequest(http.MethodGet, server.URL, nil)
+	fetch(t, client, req)
+
+	req, _ = http.NewRequest(http.MethodGet, server.URL, nil)
+	req.Header.Set("Range", "bytes=2-4")
+	var resp, body = fetch(t, client, req)
+	if resp.StatusCode != http.StatusPartialContent || body != "234" {
+		t.Errorf("did not get expected partial response %d '%s'", resp.StatusCode, body)
+	}
+	if *requests != 2 {
+		t.Errorf("did not get expected number of requests %d != 2", *requests)
+	}
+}
+
+func TestCache_BypassesCallerConditions(t *testing.T) {
+	var server, client, requests = newCachedServer(t)
+
+	var req, _ = http.NewRequest(http.MethodGet, server.URL, nil)
+	fetch(t, client, req)
+
+	req, _ = http.NewRequest(http.MethodGet, server.URL, nil)
+	req.Header.Set("If-Modified-Since", lastModified.Format(http.TimeFormat))
+	var resp, _ = fetch(t, client, req)
+	if resp.StatusCode != http.StatusNotModified {
+		t.Errorf("did not get expected status %d != %d", resp.StatusCode, http.StatusNotModified)
+	}
+	if *requests != 2 {
+		t.Errorf("did not get expected number of requests %d != 2", *requests)
+	}
+}
+
+func TestCache_DoesNotStoreCookies(t *testing.T) {
+	var server, client, requests = newCachedServer(t)
+
+	var req, _ = http.NewRequest(http.MethodGet, server.URL, nil)
+	var resp, _ = fetch(t, client, req)
+	if resp.Header.Get("Set-Cookie") == "" {
+		t.Error("did not get expected cookie on the first response")
+	}
+
+	req, _ = http.NewRequest(http.MethodGet, server.URL, nil)
+	resp, _ = fetch(t, client, req)
+	if resp.Header.Get(CacheStatusHeader) != CacheHit {
+		t.Errorf("did not get expected cache status '%s'", resp.Header.Get(CacheStatusHeader))
+	}
+	if cookie := resp.Header.Get("Set-Cookie"); cookie != "" {
+		t.Errorf("did not expect a stored cookie '%s'", cookie)
+	}
+	if *requests != 1 {
+		t.Errorf("did not get expected number of requests %d != 1", *requests)
+	}
+}
+
+func TestCache_StoresAuthenticatedResponsesOnlyWhenShared(t *testing.T) {
+	for cacheControl, expected := range map[string]int{
+		"max-age=60":         2,
+		"max-age=60, public": 1,
+	} {
+		var requests int
+		var server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			w.Header().Set("Cache-Control", cacheControl)
+			_, _ = w.Write([]byte(r.Header.Get("Authorization")))
+		}))
+		var client = &http.Client{Transport: newCacheTransport(NewMemoryCache(0), http.DefaultTransport)}
+
+		for _, token := range []string{"Bearer alice", "Bearer bob"} {
+			var req, _ = http.NewRequest(http.MethodGet, server.URL, nil)
+			req.Header.Set("Authorization", token)
+			var _, body = fetch(t, client, req)
+			if expected == 2 && body != token {
+				t.Errorf("did not get expected response '%s' != '%s'", body, token)
+			}
+		}
+		if requests != expected {
+			t.Errorf("%s: did not get expected number of requests %d != %d", cacheControl, requests, expected)
+		}
+		server.Close()
+	}
+}
@@ -0,0 +1,137 @@
+package teapot
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"gadget/settings"
+	"gadget/storage"
+)
+
+// memoryCache is a CacheStore that keeps a bounded number of entries
+// in memory, evicting the least recently used first.
+type memoryCache struct {
+	mu         sync.Mutex
+	maxEntries int
+	order      *list.List
+	items      map[string]*list.Element
+}
+
+type memoryItem struct {
+	key   string
+	value []byte
+}
+
+// NewMemoryCache creates an in-memory LRU CacheStore; a maxEntries
+// of zero or less means the number of entries is not limited.
+func NewMemoryCache(maxEntries int) *memoryCache {
+	return &memoryCache{maxEntries: maxEntries, order: list.New(), items: make(map[string]*list.Element)}
+}
+
+func (cache *memoryCache) Get(key string) ([]byte, bool) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	var elem, ok = cache.items[key]
+	if !ok {
+		return nil, false
+	}
+	cache.order.MoveToFront(elem)
+	return elem.Value.(*memoryItem).value, true
+}
+
+func (cache *memoryCache) Set(key string, value []byte) error {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	if elem, ok := cache.items[key]; ok {
+		elem.Value.(*memoryItem).value = value
+		cache.order.MoveToFront(elem)
+		return nil
+	}
+
+	cache.items[key] = cache.order.PushFront(&memoryItem{key: key, value: value})
+	for cache.maxEntries > 0 && cache.order.Len() > cache.maxEntries {
+		var oldest = cache.order.Back()
+		cache.order.Remove(oldest)
+		delete(cache.items, oldest.Value.(*memoryItem).key)
+	}
+	return nil
+}
+
+func (cache *memoryCache) Delete(key string) error {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	if elem, ok := cache.items[key]; ok {
+		cache.order.Remove(elem)
+		delete(cache.items, key)
+	}
+	return nil
+}
+
+// diskCache is a CacheStore that keeps one file per entry in a directory.
+type diskCache struct {
+	dir string
+}
+
+// NewDiskCache creates a CacheStore persisting entries under dir.
+func NewDiskCache(dir string) *diskCache {
+	return &diskCache{dir: dir}
+}
+
+// NewUserDiskCache creates a CacheStore in the user cache directory of the namespace.
+func NewUserDiskCache(dirs settings.UserDirs) *diskCache {
+	return NewDiskCache(filepath.Join(dirs.Cache(), "teapot"))
+}
+
+func (cache *diskCache) path(key string) string {
+	var sum = sha256.Sum256([]byte(key))
+	return filepath.Join(cache.dir, hex.EncodeToString(sum[:]))
+}
+
+func (cache *diskCache) Get(key string) ([]byte, bool) {
+	var content, err = os.ReadFile(cache.path(key))
+	if err != nil {
+		return nil, false
+	}
+	return content, true
+}
+
+// Set writes to a temporary file first so readers never see a partial entry.
+func (cache *diskCache) Set(key string, value []byte) error {
+	var err error
+	var tmp *os.File
+
+	if err = os.MkdirAll(cache.dir, storage.MinDirPermission); err != nil {
+		return err
+	}
+	if tmp, err = os.CreateTemp(cache.dir, ".entry-*"); err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err = tmp.Write(value); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	if err = os.Chmod(tmp.Name(), storage.MinFilePermission); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), cache.path(key))
+}
+
+func (cache *diskCache) Delete(key string) error {
+	if err := os.Remove(cache.path(key)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,175 @@
+package chromium
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/driver/sqlite" // Sqlite driver based on GGO
+	"gorm.io/gorm"
+
+	"gadget/teapot/cookiejar"
+)
+
+// hashedValuesVersion is the first schema version that prefixes decrypted
+// values with the SHA-256 of the host.
+const hashedValuesVersion = 24
+
+// chromecookies implements the gadget/teapot/cookiejar.Loader interface.
+type chromecookies struct {
+	core  *chromiumCore
+	jar   http.CookieJar
+	table string
+}
+
+func NewCookieLoader(jar http.CookieJar, options ...Option) *chromecookies {
+	var loader = &chromecookies{
+		core:  newChromiumCore(options...),
+		jar:   jar,
+		table: "cookies",
+	}
+
+	return loader
+}
+
+func (loader *chromecookies) SetJar(jar http.CookieJar) cookiejar.Loader {
+	loader.jar = jar
+	return loader
+}
+
+func (loader *chromecookies) ToJar(jar http.CookieJar, keys ...*url.URL) {
+	for _, key := range keys {
+		jar.SetCookies(key, loader.jar.Cookies(key))
+	}
+}
+
+func (loader *chromecookies) Jar() http.CookieJar {
+	return loader.jar
+}
+
+// Load sets the unexpired cookies of the profile in the jar, only those of
+// the hosts if any are given; cookies that cannot be decrypted are skipped
+// and only fail the load if no other cookie is left.
+func (loader *chromecookies) Load(hosts ...string) error {
+	var err error
+	var db *gorm.DB
+	var query *gorm.DB
+	var dbpath string
+	var chromecookies []ChromiumSQLiteCookie
+
+	if err = loader.core.valid(); err != nil {
+		return err
+	}
+	if loader.jar == nil {
+		return new(cookiejar.NilCookieJarError)
+	}
+
+	var log = loader.core.log
+	if dbpath, err = loader.core.cookieDBPath(); err != nil {
+		return err
+	}
+	log.Debugf("loading Chromium cookies: %s", dbpath)
+
+	if db, err = gorm.Open(sqlite.Open(dbpath), &gorm.Config{}); err != nil {
+		return err
+	}
+	var hashed = loader.schemaVersion(db) >= hashedValuesVersion
+
+	query = db.Table(loader.table)
+	if keys := hostKeys(hosts); len(keys) != 0 {
+		query = query.Where("host_key IN ?", keys)
+	}
+	if query = query.Find(&chromecookies); query.Error != nil {
+		return query.Error
+	}
+	log.Debugf("%d cookies found", len(chromecookies))
+
+	var now = time.Now()
+	var cookies = make(map[string][]*http.Cookie)
+	var locations = make(map[string]*url.URL)
+	var failed []error
+	for index := range chromecookies {
+		var chromecookie = &chromecookies[index]
+		var expires = chromecookie.Expires()
+		if !expires.IsZero() && !expires.After(now) {
+			continue
+		}
+
+		var value = chromecookie.Value
+		if value == "" && len(chromecookie.EncryptedValue) != 0 {
+			var decryptErr error
+			if value, decryptErr = decrypt(loader.core.keys, chromecookie, hashed); decryptErr != nil {
+				// e.g. a keyring secret the provider does not know about
+				log.Warnf("skipping cookie: %v", decryptErr)
+				failed = append(failed, decryptErr)
+				continue
+			}
+		}
+
+		// a leading dot marks a cookie that is sent to subdomains as well
+		var host = strings.TrimPrefix(chromecookie.HostKey, ".")
+		var httpcookie = &http.Cookie{
+			Name:     chromecookie.Name,
+			Value:    value,
+			Path:     chromecookie.Path,
+			Expires:  expires,
+			Secure:   chromecookie.Secure,
+			HttpOnly: chromecookie.HttpOnly,
+			SameSite: chromecookie.sameSite(),
+		}
+		if strings.HasPrefix(chromecookie.HostKey, ".") {
+			httpcookie.Domain = host
+		}
+
+		// NOTE: cookiejar.Jar will ignore any cookies you try to set without a Scheme!
+		var loc = &url.URL{Scheme: "http", Host: host}
+		if chromecookie.Secure {
+			loc.Scheme = "https"
+		}
+		var key = loc.String()
+		locations[key] = loc
+		cookies[key] = append(cookies[key], httpcookie)
+	}
+	if len(cookies) == 0 && len(failed) != 0 {
+		return errors.Join(failed...)
+	} else if len(cookies) == 0 {
+		return &cookiejar.NoCookiesFoundError{Hosts: hosts}
+	}
+	if len(failed) != 0 {
+		log.Warnf("%d cookies could not be decrypted", len(failed))
+	}
+
+	for key, list := range cookies {
+		loader.jar.SetCookies(locations[key], list)
+	}
+	log.Debugf("cookiejar loaded")
+
+	return err
+}
+
+// schemaVersion reads the version of the database from its meta table.
+func (loader *chromecookies) schemaVersion(db *gorm.DB) int {
+	var values []string
+	if err := db.Table("meta").Where("key = ?", "version").Pluck("value", &values).Error; err != nil || len(values) == 0 {
+		return 0
+	}
+	var version, _ = strconv.Atoi(values[0])
+	return version
+}
+
+// hostKeys returns the host_key values of the hosts, which are stored with
+// a leading dot for cookies that match subdomains.
+func hostKeys(hosts []string) []string {
+	var keys = make([]string, 0, 2*len(hosts))
+	for _, host := range hosts {
+		host = strings.TrimPrefix(strings.TrimSpace(host), ".")
+		if host == "" {
+			continue
+		}
+		keys = append(keys, host, "."+host)
+	}
+	return keys
+}
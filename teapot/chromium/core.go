@@ -0,0 +1,108 @@
+package chromium
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+
+	"gadget/logging"
+)
+
+// DefaultProfile is the profile Chromium creates on first start.
+const DefaultProfile = "Default"
+
+type Option func(core *chromiumCore)
+
+func UseLogger(log logging.Logger) Option {
+	return func(core *chromiumCore) {
+		core.log = log
+	}
+}
+
+// UseDataPath sets the user data directory, `~/.config/chromium` on Linux
+// by default; Google Chrome uses `~/.config/google-chrome` instead.
+func UseDataPath(path string) Option {
+	return func(core *chromiumCore) {
+		core.datapath = path
+	}
+}
+
+// UseProfile sets the profile directory within the data path, e.g.
+// `Profile 1`; DefaultProfile if not set.
+func UseProfile(profile string) Option {
+	return func(core *chromiumCore) {
+		core.profile = profile
+	}
+}
+
+// UseKeyProvider sets where the keys for encrypted cookie values come
+// from; LinuxFixedKey if not set.
+func UseKeyProvider(keys KeyProvider) Option {
+	return func(core *chromiumCore) {
+		core.keys = keys
+	}
+}
+
+type chromiumCore struct {
+	log      logging.Logger
+	datapath string
+	profile  string
+	keys     KeyProvider
+}
+
+func newChromiumCore(options ...Option) *chromiumCore {
+	var core = new(chromiumCore)
+	for _, option := range options {
+		option(core)
+	}
+	if core.log == nil {
+		core.log = logging.NewNoopLogger()
+	}
+	if core.keys == nil {
+		core.keys = LinuxFixedKey
+	}
+	return core
+}
+
+func (core *chromiumCore) valid() error {
+	var err error
+
+	if err = core.load(); err != nil {
+		return err
+	}
+
+	return err
+}
+
+func (core *chromiumCore) load() error {
+	if core.datapath == "" {
+		var config, err = os.UserConfigDir()
+		if err != nil {
+			return err
+		}
+		core.datapath = filepath.Join(config, "chromium")
+	}
+	if core.profile == "" {
+		core.profile = DefaultProfile
+	}
+	if _, err := os.Stat(filepath.Join(core.datapath, core.profile)); err != nil {
+		return &ProfileError{Path: filepath.Join(core.datapath, core.profile), Err: err}
+	}
+	return nil
+}
+
+// cookieDBPath returns the location of the cookie database, which moved
+// into the Network directory of the profile in Chromium 96.
+func (core *chromiumCore) cookieDBPath() (string, error) {
+	for _, path := range []string{
+		filepath.Join(core.datapath, core.profile, "Network", "Cookies"),
+		filepath.Join(core.datapath, core.profile, "Cookies"),
+	} {
+		if _, err := os.Stat(path); err == nil {
+			return "file:" + path + "?immutable=1", nil
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return "", err
+		}
+	}
+	return "", new(EmptySqlitePathError)
+}
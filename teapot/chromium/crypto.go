@@ -0,0 +1,116 @@
+package chromium
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha1"
+	"crypto/sha256"
+	"sync"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// KeyProvider returns the AES key for cookie values encrypted with the
+// version prefix, either `v10` or `v11`; the key of an empty password is
+// tried as well for `v11` values the key does not decrypt.
+//
+// On Linux `v10` values always use a key derived from a fixed password,
+// while `v11` values use a password kept in the keyring of the desktop,
+// e.g. the `Chromium Safe Storage` secret of libsecret or KWallet. Reading
+// the keyring is left to the caller, see PasswordKey; when no keyring is
+// available Chromium falls back to the fixed password for both versions.
+type KeyProvider interface {
+	Key(version string) ([]byte, error)
+}
+
+// KeyProviderFunc adapts a func to the KeyProvider interface.
+type KeyProviderFunc func(version string) ([]byte, error)
+
+func (fn KeyProviderFunc) Key(version string) ([]byte, error) {
+	return fn(version)
+}
+
+// LinuxFixedKey is the key Chromium on Linux uses without a keyring.
+var LinuxFixedKey = PasswordKey("peanuts", 1)
+
+// emptyPasswordKey is tried for `v11` values when the key of the provider
+// fails, since Chromium uses an empty password when the keyring is
+// reachable but holds no secret.
+var emptyPasswordKey = PasswordKey("", 1)
+
+// PasswordKey derives the key from a password the way Chromium does, with
+// PBKDF2-SHA1 and the salt `saltysalt`; Linux uses 1 iteration and macOS,
+// with the password from the Keychain, 1003.
+func PasswordKey(password string, iterations int) KeyProvider {
+	var once sync.Once
+	var key []byte
+	return KeyProviderFunc(func(string) ([]byte, error) {
+		once.Do(func() {
+			key = pbkdf2.Key([]byte(password), []byte("saltysalt"), iterations, aes.BlockSize, sha1.New)
+		})
+		return key, nil
+	})
+}
+
+// decrypt returns the plain value of an encrypted cookie; hashed is true
+// if the database prefixes values with the SHA-256 of the host, which it
+// does since version 24 of its schema.
+func decrypt(keys KeyProvider, cookie *ChromiumSQLiteCookie, hashed bool) (string, error) {
+	var data = cookie.EncryptedValue
+	if len(data) < 3 {
+		return "", &DecryptError{Name: cookie.Name, Host: cookie.HostKey, Reason: "value too short"}
+	}
+	var version = string(data[:3])
+	if version != "v10" && version != "v11" {
+		return "", &DecryptError{Name: cookie.Name, Host: cookie.HostKey, Reason: "unknown version " + version}
+	}
+	data = data[3:]
+	if len(data) == 0 || len(data)%aes.BlockSize != 0 {
+		return "", &DecryptError{Name: cookie.Name, Host: cookie.HostKey, Reason: "value is not a multiple of the block size"}
+	}
+
+	var candidates = []KeyProvider{keys}
+	if version == "v11" {
+		candidates = append(candidates, emptyPasswordKey)
+	}
+	var sum = sha256.Sum256([]byte(cookie.HostKey))
+	for _, candidate := range candidates {
+		var plain, err = decryptCBC(candidate, version, data)
+		if err != nil {
+			return "", err
+		}
+		if plain == nil {
+			continue
+		}
+		if !hashed {
+			return string(plain), nil
+		}
+		if len(plain) >= len(sum) && bytes.Equal(plain[:len(sum)], sum[:]) {
+			return string(plain[len(sum):]), nil
+		}
+	}
+	return "", &DecryptError{Name: cookie.Name, Host: cookie.HostKey, Reason: "the key is probably wrong"}
+}
+
+// decryptCBC returns the unpadded value or nil if the padding shows the
+// key is wrong.
+func decryptCBC(keys KeyProvider, version string, data []byte) ([]byte, error) {
+	var key, err = keys.Key(version)
+	if err != nil {
+		return nil, err
+	}
+	var block cipher.Block
+	if block, err = aes.NewCipher(key); err != nil {
+		return nil, err
+	}
+	var plain = make([]byte, len(data))
+	cipher.NewCBCDecrypter(block, bytes.Repeat([]byte{' '}, aes.BlockSize)).CryptBlocks(plain, data)
+
+	// PKCS#7 padding, which is also where a wrong key shows
+	var padding = int(plain[len(plain)-1])
+	if padding == 0 || padding > aes.BlockSize || !bytes.Equal(plain[len(plain)-padding:], bytes.Repeat([]byte{byte(padding)}, padding)) {
+		return nil, nil
+	}
+	return plain[:len(plain)-padding], nil
+}
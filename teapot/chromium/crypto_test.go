@@ -0,0 +1,74 @@
+package chromium
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+func encrypted(t *testing.T, version string, ciphertext string) []byte {
+	t.Helper()
+	var data, err = hex.DecodeString(ciphertext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return append([]byte(version), data...)
+}
+
+func TestLinuxFixedKey(t *testing.T) {
+	var key, _ = LinuxFixedKey.Key("v10")
+	if hex.EncodeToString(key) != "fd621fe5a2b402539dfa147ca9272778" {
+		t.Errorf("did not get expected key %x", key)
+	}
+}
+
+func TestDecrypt_KnownAnswers(t *testing.T) {
+	var cases = []struct {
+		name   string
+		host   string
+		value  []byte
+		hashed bool
+		want   string
+	}{
+		{
+			name:  "v10",
+			host:  ".example.com",
+			value: encrypted(t, "v10", "16ec0ac044907134e7d64152a751932a"),
+			want:  "secret-value",
+		},
+		{
+			name:   "v10 with host hash",
+			host:   ".example.com",
+			value:  encrypted(t, "v10", "1bb654833fa6f8a731528db92d657dc37c87544a81ba7875d0681273543b4e4fe0c998dab951a86357de383e41fc31f7"),
+			hashed: true,
+			want:   "secret-value",
+		},
+		{
+			name:  "v11 with empty password",
+			host:  "example.org",
+			value: encrypted(t, "v11", "f1dcae7fd89e373143ba58b5a33e9e38"),
+			want:  "keyring-less",
+		},
+	}
+	for _, tc := range cases {
+		var cookie = &ChromiumSQLiteCookie{Name: "sid", HostKey: tc.host, EncryptedValue: tc.value}
+		var got, err = decrypt(LinuxFixedKey, cookie, tc.hashed)
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("%s: got %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestDecrypt_WrongHost(t *testing.T) {
+	var cookie = &ChromiumSQLiteCookie{
+		Name:           "sid",
+		HostKey:        ".example.org",
+		EncryptedValue: encrypted(t, "v10", "1bb654833fa6f8a731528db92d657dc37c87544a81ba7875d0681273543b4e4fe0c998dab951a86357de383e41fc31f7"),
+	}
+	if _, err := decrypt(LinuxFixedKey, cookie, true); err == nil {
+		t.Error("expected an error for a value hashed with another host")
+	}
+}
@@ -0,0 +1,82 @@
+package chromium
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+type EmptySqlitePathError struct{}
+
+func (e *EmptySqlitePathError) Error() string {
+	return "Empty path to sqlite Chromium cookie database"
+}
+
+type ProfileError struct {
+	Path string
+	Err  error
+}
+
+func (e *ProfileError) Error() string {
+	return fmt.Sprintf("Chromium profile not found at %s: %v", e.Path, e.Err)
+}
+
+func (e *ProfileError) Unwrap() error {
+	return e.Err
+}
+
+type DecryptError struct {
+	Name   string
+	Host   string
+	Reason string
+}
+
+func (e *DecryptError) Error() string {
+	return fmt.Sprintf("cannot decrypt cookie %s of %s: %s", e.Name, e.Host, e.Reason)
+}
+
+// windowsEpoch is the number of seconds between 1601-01-01, which Chromium
+// counts its timestamps from, and the unix epoch.
+const windowsEpoch = 11644473600
+
+// ChromiumSQLiteCookie
+//
+// https://source.chromium.org/chromium/chromium/src/+/main:net/extras/sqlite/sqlite_persistent_cookie_store.cc
+type ChromiumSQLiteCookie struct {
+	CreationUTC    int64  `gorm:"column:creation_utc"`
+	HostKey        string `gorm:"column:host_key"`
+	Name           string `gorm:"column:name"`
+	Value          string `gorm:"column:value"`
+	EncryptedValue []byte `gorm:"column:encrypted_value"`
+	Path           string `gorm:"column:path"`
+	ExpiresUTC     int64  `gorm:"column:expires_utc"`
+	Secure         bool   `gorm:"column:is_secure"`
+	HttpOnly       bool   `gorm:"column:is_httponly"`
+	LastAccessUTC  int64  `gorm:"column:last_access_utc"`
+	HasExpires     bool   `gorm:"column:has_expires"`
+	Persistent     bool   `gorm:"column:is_persistent"`
+	SameSite       int64  `gorm:"column:samesite"`
+}
+
+// Expires converts the expiry, in microseconds since 1601, to a time;
+// it is zero for session cookies.
+func (cookie *ChromiumSQLiteCookie) Expires() time.Time {
+	if cookie.ExpiresUTC == 0 || !cookie.HasExpires {
+		return time.Time{}
+	}
+	return time.Unix(cookie.ExpiresUTC/1000000-windowsEpoch, 0)
+}
+
+// sameSite maps the Chromium values, where -1 is unspecified, to http.SameSite.
+func (cookie *ChromiumSQLiteCookie) sameSite() http.SameSite {
+	switch cookie.SameSite {
+	case 0:
+		return http.SameSiteNoneMode
+	case 1:
+		return http.SameSiteLaxMode
+	case 2:
+		return http.SameSiteStrictMode
+	default:
+		return http.SameSiteDefaultMode
+	}
+}
@@ -33,6 +33,23 @@ type Config struct {
 	// TLSConfig represents the `TLSClientConfig` field of the `http.Transport`.
 	TLS *TLSConfig `mapstructure:"tls" json:"tls,omitempty"`
 
+	// Retry determines whether and how failed requests are attempted again.
+	Retry *RetryConfig `mapstructure:"retry" json:"retry,omitempty"`
+
+	// RateLimits paces requests per host; the first matching entry applies.
+	// The limits are shared by every Session and clone of the Teapot.
+	RateLimits []*HostLimit `mapstructure:"rate_limits" json:"rate_limits,omitempty"`
+
+	// Proxy routes requests through proxies; the environment is used if nil.
+	Proxy *ProxyConfig `mapstructure:"proxy" json:"proxy,omitempty"`
+
+	// CircuitBreaker stops requests to hosts that keep failing when set.
+	// The circuits are shared by every Session and clone of the Teapot.
+	CircuitBreaker *CircuitBreakerConfig `mapstructure:"circuit_breaker" json:"circuit_breaker,omitempty"`
+
+	// AccessLog logs every request with the Logger when set.
+	AccessLog *AccessLogConfig `mapstructure:"access_log" json:"access_log,omitempty"`
+
 	// TODO: WIP
 	// Servers []*ServerConfig `mapstructure:"servers" json:"servers,omitempty"`
 }
@@ -235,11 +252,33 @@ type TLSConfig struct {
 	// possible to set this field to VersionTLS10 explicitly).
 	MinVersion uint16 `mapstructure:"min_version" json:"min_version,omitempty"`
 
+	// ServerName overrides the name used to verify the certificate of the
+	// server and sent with SNI, e.g. when connecting by IP address.
+	ServerName string `mapstructure:"server_name" json:"server_name,omitempty"`
+
+	// CAPaths are PEM files of certificate authorities, e.g. of a private
+	// PKI, that are trusted in addition to the system roots.
+	CAPaths []string `mapstructure:"capaths" json:"capaths,omitempty"`
+
+	// ReplaceSystemRoots trusts only the CAPaths instead of adding them
+	// to the certificate authorities of the system.
+	ReplaceSystemRoots bool `mapstructure:"replace_system_roots" json:"replace_system_roots,omitempty"`
+
+	// CertPath and KeyPath are the PEM files of a client certificate for
+	// mutual TLS. They are reloaded when they change on disk so that
+	// rotated certificates are used without a restart.
+	CertPath string `mapstructure:"certpath" json:"certpath,omitempty"`
+	KeyPath  string `mapstructure:"keypath" json:"keypath,omitempty"`
+
+	// CipherSuites restricts the cipher suites used up to TLS 1.2 by their
+	// standard names, e.g. `TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256`.
+	CipherSuites []string `mapstructure:"cipher_suites" json:"cipher_suites,omitempty"`
+
+	// Pins restricts the public keys accepted from the hosts they match.
+	Pins []*PublicKeyPin `mapstructure:"pins" json:"pins,omitempty"`
+
 	// Bindport     int           `mapstructure:"bind_port" json:"bind_port"`
 	// BindAddress  string        `mapstructure:"bind_address" json:"bind_address"`
-	// CAPath       string        `mapstructure:"capath" json:"capath"`
-	// CertPath     string        `mapstructure:"certpath" json:"certpath"`
-	// KeyPath      string        `mapstructure:"keypath" json:"keypath"`
 	// ReadTimeout  time.Duration `mapstructure:"read_timeout" json:"read_timeout"`
 	// WriteTimeout time.Duration `mapstructure:"write_timeout" json:"write_timeout"`
 }
@@ -251,11 +290,72 @@ func (cfg TLSConfig) Construct() *tls.Config {
 	return config
 }
 
+// Build is Construct returning the error of loading the CA and certificate files.
+func (cfg TLSConfig) Build() (*tls.Config, error) {
+	var config = new(tls.Config)
+	if err := cfg.Configure(config); err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
 // Apply will update a `*tls.Config`.
+//
+// If the configuration cannot be loaded every handshake fails with the
+// error instead of connecting with less verification than was asked for;
+// use Configure to get the error right away.
 func (cfg TLSConfig) Apply(config *tls.Config) {
+	if err := cfg.Configure(config); err != nil && config != nil {
+		config.VerifyConnection = func(tls.ConnectionState) error {
+			return err
+		}
+	}
+}
+
+// Configure will update a `*tls.Config`, loading the CA and certificate files.
+func (cfg TLSConfig) Configure(config *tls.Config) error {
 	if config == nil {
-		return
+		return nil
 	}
 	config.InsecureSkipVerify = cfg.InsecureSkipVerify
 	config.MinVersion = cfg.MinVersion
+
+	if cfg.ServerName != "" {
+		config.ServerName = cfg.ServerName
+	}
+
+	if len(cfg.CAPaths) != 0 || cfg.ReplaceSystemRoots {
+		var pool, err = loadCertPool(cfg.CAPaths, cfg.ReplaceSystemRoots)
+		if err != nil {
+			return err
+		}
+		config.RootCAs = pool
+	}
+
+	if cfg.CertPath != "" || cfg.KeyPath != "" {
+		var reloader, err = newCertReloader(cfg.CertPath, cfg.KeyPath)
+		if err != nil {
+			return err
+		}
+		config.GetClientCertificate = reloader.clientCertificate
+	}
+
+	if len(cfg.CipherSuites) != 0 {
+		var suites, err = cipherSuites(cfg.CipherSuites)
+		if err != nil {
+			return err
+		}
+		config.CipherSuites = suites
+	}
+
+	if len(cfg.Pins) != 0 {
+		var pins, err = newPinSet(cfg.Pins)
+		if err != nil {
+			return err
+		}
+		config.VerifyConnection = pins.verifier(config)
+	}
+
+	return nil
 }
@@ -0,0 +1,306 @@
+package cookiejar
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	netscapeHeader   = "# Netscape HTTP Cookie File"
+	netscapeHttpOnly = "#HttpOnly_"
+)
+
+// netscapeCookies implements the Loader interface for the Netscape
+// cookies.txt format used by curl, wget, yt-dlp and many browser extensions.
+//
+// Every line holds one cookie as seven tab separated fields: the domain,
+// whether subdomains match, the path, whether the cookie is secure, the
+// expiry in unix seconds (zero for session cookies), the name and the value.
+// Lines starting with `#` are comments, except for the `#HttpOnly_` prefix
+// curl puts in front of the domain of HttpOnly cookies.
+//
+// https://curl.se/docs/http-cookies.html
+type netscapeCookies struct {
+	jar  http.CookieJar
+	path string
+}
+
+// NewNetscapeLoader constructs a Loader for the cookies.txt file at path.
+func NewNetscapeLoader(jar http.CookieJar, path string) *netscapeCookies {
+	return &netscapeCookies{jar: jar, path: path}
+}
+
+func (loader *netscapeCookies) SetJar(jar http.CookieJar) Loader {
+	loader.jar = jar
+	return loader
+}
+
+func (loader *netscapeCookies) ToJar(jar http.CookieJar, keys ...*url.URL) {
+	for _, key := range keys {
+		jar.SetCookies(key, loader.jar.Cookies(key))
+	}
+}
+
+func (loader *netscapeCookies) Jar() http.CookieJar {
+	return loader.jar
+}
+
+// Load sets the cookies of the file in the jar, only those of the hosts if
+// any are given; expired cookies are skipped.
+func (loader *netscapeCookies) Load(hosts ...string) error {
+	if loader.jar == nil {
+		return new(NilCookieJarError)
+	}
+
+	var file, err = os.Open(loader.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var entries []netscapeCookie
+	if entries, err = readNetscape(file); err != nil {
+		return err
+	}
+
+	var now = time.Now()
+	var loaded int
+	for _, entry := range entries {
+		if entry.expired(now) || !entry.matches(hosts) {
+			continue
+		}
+		loader.jar.SetCookies(entry.location(), []*http.Cookie{entry.cookie})
+		loaded++
+	}
+	if loaded == 0 {
+		return &NoCookiesFoundError{Hosts: hosts}
+	}
+	return nil
+}
+
+// netscapeCookie is a single line of a cookies.txt file.
+type netscapeCookie struct {
+	domain     string
+	subdomains bool
+	cookie     *http.Cookie
+}
+
+// readNetscape parses a cookies.txt file.
+func readNetscape(r io.Reader) ([]netscapeCookie, error) {
+	var entries []netscapeCookie
+	var scanner = bufio.NewScanner(r)
+	var number int
+	for scanner.Scan() {
+		number++
+		var line = strings.TrimRight(scanner.Text(), "\r")
+		var httpOnly bool
+		if strings.HasPrefix(line, netscapeHttpOnly) {
+			line = strings.TrimPrefix(line, netscapeHttpOnly)
+			httpOnly = true
+		} else if strings.HasPrefix(line, "#") || strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		// the value may be missing entirely instead of being empty
+		var fields = strings.Split(line, "\t")
+		if len(fields) == 6 {
+			fields = append(fields, "")
+		}
+		if len(fields) != 7 {
+			return nil, &CookieError{Message: fmt.Sprintf("cookies.txt line %d: expected 7 fields, found %d", number, len(fields))}
+		}
+
+		var expiry, err = strconv.ParseInt(fields[4], 10, 64)
+		if err != nil {
+			return nil, &CookieError{Message: fmt.Sprintf("cookies.txt line %d: invalid expiry %q", number, fields[4])}
+		}
+		var entry = netscapeCookie{
+			domain:     strings.ToLower(strings.TrimPrefix(fields[0], ".")),
+			subdomains: strings.EqualFold(fields[1], "TRUE"),
+			cookie: &http.Cookie{
+				Path:     fields[2],
+				Secure:   strings.EqualFold(fields[3], "TRUE"),
+				Name:     fields[5],
+				Value:    fields[6],
+				HttpOnly: httpOnly,
+			},
+		}
+		if entry.domain == "" {
+			return nil, &CookieError{Message: fmt.Sprintf("cookies.txt line %d: no domain", number)}
+		}
+		if expiry > 0 {
+			entry.cookie.Expires = time.Unix(expiry, 0)
+		}
+		// a Domain attribute is what makes a cookie match subdomains
+		if entry.subdomains {
+			entry.cookie.Domain = entry.domain
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+// location is the URL the cookie is set for.
+func (entry *netscapeCookie) location() *url.URL {
+	var loc = &url.URL{Scheme: "http", Host: entry.domain, Path: entry.cookie.Path}
+	if entry.cookie.Secure {
+		loc.Scheme = "https"
+	}
+	return loc
+}
+
+func (entry *netscapeCookie) expired(now time.Time) bool {
+	return !entry.cookie.Expires.IsZero() && !entry.cookie.Expires.After(now)
+}
+
+// matches reports whether the cookie is sent to any of the hosts, or true
+// if there are none.
+func (entry *netscapeCookie) matches(hosts []string) bool {
+	if len(hosts) == 0 {
+		return true
+	}
+	for _, host := range hosts {
+		host = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(host), "."))
+		if host == entry.domain || entry.subdomains && strings.HasSuffix(host, "."+entry.domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// line formats the cookie as a cookies.txt line.
+func (entry *netscapeCookie) line() string {
+	var domain = entry.domain
+	if entry.subdomains {
+		domain = "." + domain
+	}
+	if entry.cookie.HttpOnly {
+		domain = netscapeHttpOnly + domain
+	}
+	var expiry int64
+	if !entry.cookie.Expires.IsZero() {
+		expiry = entry.cookie.Expires.Unix()
+	}
+	var path = entry.cookie.Path
+	if path == "" {
+		path = "/"
+	}
+	return strings.Join([]string{
+		domain,
+		netscapeBool(entry.subdomains),
+		path,
+		netscapeBool(entry.cookie.Secure),
+		strconv.FormatInt(expiry, 10),
+		entry.cookie.Name,
+		entry.cookie.Value,
+	}, "\t")
+}
+
+func netscapeBool(value bool) string {
+	if value {
+		return "TRUE"
+	}
+	return "FALSE"
+}
+
+// ExportNetscape writes the cookies of the jar in the cookies.txt format.
+//
+// The std lib jar only hands out the names and values of the cookies that
+// would be sent to a location, so for most jars the locations are required
+// and every cookie is written as a session cookie of the host with the
+// root path; without locations a CookieError is returned. A persistent jar
+// knows all of its cookies, which are written with every attribute; the
+// locations only narrow down the hosts then.
+func ExportNetscape(w io.Writer, jar http.CookieJar, locations ...*url.URL) error {
+	var entries []netscapeCookie
+	if jar == nil {
+		return new(NilCookieJarError)
+	} else if persistent, ok := jar.(*persistentJar); ok {
+		entries = persistent.netscape(locations)
+	} else if len(locations) == 0 {
+		return &CookieError{Message: "exporting cookies.txt requires locations for a jar that cannot list its cookies"}
+	} else {
+		for _, loc := range locations {
+			for _, cookie := range jar.Cookies(loc) {
+				entries = append(entries, netscapeCookie{
+					domain: strings.ToLower(loc.Hostname()),
+					cookie: &http.Cookie{Name: cookie.Name, Value: cookie.Value, Path: "/", Secure: loc.Scheme == "https"},
+				})
+			}
+		}
+	}
+
+	var content bytes.Buffer
+	content.WriteString(netscapeHeader + "\n")
+	content.WriteString("# https://curl.se/docs/http-cookies.html\n\n")
+	for index := range entries {
+		content.WriteString(entries[index].line() + "\n")
+	}
+	var _, err = w.Write(content.Bytes())
+	return err
+}
+
+// SaveNetscape writes the cookies of the jar to a cookies.txt file at path,
+// which is only readable by the user; see ExportNetscape.
+func SaveNetscape(path string, jar http.CookieJar, locations ...*url.URL) error {
+	var content bytes.Buffer
+	if err := ExportNetscape(&content, jar, locations...); err != nil {
+		return err
+	}
+	return writeFile(path, content.Bytes())
+}
+
+// netscape returns the unexpired cookies of the jar with their original
+// names, limited to those sent to the locations if any are given.
+func (jar *persistentJar) netscape(locations []*url.URL) []netscapeCookie {
+	jar.mu.Lock()
+	defer jar.mu.Unlock()
+
+	var hosts = make([]string, 0, len(locations))
+	for _, loc := range locations {
+		hosts = append(hosts, loc.Hostname())
+	}
+
+	var now = time.Now()
+	var entries = make([]netscapeCookie, 0, len(jar.records))
+	for _, record := range jar.records {
+		if record.expired(now) {
+			continue
+		}
+		var cookie = record.cookie()
+		if original, ok := jar.nameMap[cookie.Name]; ok {
+			cookie.Name = original
+		}
+		var entry = netscapeCookie{
+			domain:     strings.ToLower(strings.TrimPrefix(record.Domain, ".")),
+			subdomains: record.Domain != "",
+			cookie:     cookie,
+		}
+		var uri, err = url.Parse(record.URL)
+		if err != nil {
+			continue
+		}
+		if !entry.subdomains {
+			entry.domain = strings.ToLower(uri.Hostname())
+		}
+		if !strings.HasPrefix(cookie.Path, "/") {
+			cookie.Path = defaultPath(uri.Path)
+		}
+		if entry.matches(hosts) {
+			entries = append(entries, entry)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].line() < entries[j].line()
+	})
+	return entries
+}
@@ -0,0 +1,145 @@
+package cookiejar
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const netscapeFixture = "# Netscape HTTP Cookie File\r\n" +
+	"# comment\n" +
+	"\n" +
+	".example.com\tTRUE\t/\tTRUE\t4102444800\tsid\tabc\r\n" +
+	"#HttpOnly_www.example.com\tFALSE\t/app\tFALSE\t0\ttoken\txyz\n" +
+	"example.org\tFALSE\t/\tFALSE\t0\tempty\n" +
+	"old.example.net\tFALSE\t/\tFALSE\t946684800\tstale\tgone\n"
+
+func TestReadNetscape(t *testing.T) {
+	var entries, err = readNetscape(strings.NewReader(netscapeFixture))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 4 {
+		t.Fatalf("did not get expected number of entries %d != 4", len(entries))
+	}
+
+	var sid = entries[0]
+	if sid.domain != "example.com" || !sid.subdomains || sid.cookie.Domain != "example.com" ||
+		!sid.cookie.Secure || sid.cookie.Expires.Unix() != 4102444800 || sid.cookie.Value != "abc" {
+		t.Errorf("did not get expected cookie: %+v %+v", sid, sid.cookie)
+	}
+	var token = entries[1]
+	if token.domain != "www.example.com" || token.subdomains || token.cookie.Domain != "" ||
+		!token.cookie.HttpOnly || token.cookie.Path != "/app" || !token.cookie.Expires.IsZero() {
+		t.Errorf("did not get expected HttpOnly cookie: %+v %+v", token, token.cookie)
+	}
+	if empty := entries[2]; empty.cookie.Name != "empty" || empty.cookie.Value != "" {
+		t.Errorf("did not get expected cookie without value: %+v", empty.cookie)
+	}
+
+	// every line survives being written again
+	for index := range entries {
+		var line = entries[index].line()
+		var again, err = readNetscape(strings.NewReader(line))
+		if err != nil || len(again) != 1 || again[0].line() != line {
+			t.Errorf("did not get expected line back '%s' (error=%v)", line, err)
+		}
+	}
+}
+
+func TestReadNetscape_Invalid(t *testing.T) {
+	for name, content := range map[string]string{
+		"fields": "example.com\tFALSE\t/\tFALSE\t0\n",
+		"expiry": "example.com\tFALSE\t/\tFALSE\tsoon\tname\tvalue\n",
+		"domain": "\tFALSE\t/\tFALSE\t0\tname\tvalue\n",
+	} {
+		var _, err = readNetscape(strings.NewReader(content))
+		var cookieErr *CookieError
+		if !errors.As(err, &cookieErr) {
+			t.Errorf("%s: did not get expected CookieError: %v", name, err)
+		}
+	}
+}
+
+func TestNetscapeLoader_Load(t *testing.T) {
+	var path = filepath.Join(t.TempDir(), "cookies.txt")
+	if err := os.WriteFile(path, []byte(netscapeFixture), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	var jar = New()
+	if err := NewNetscapeLoader(jar, path).Load("shop.example.com"); err != nil {
+		t.Fatal(err)
+	}
+	var loc, _ = url.Parse("https://shop.example.com/")
+	if cookies := jar.Cookies(loc); len(cookies) != 1 || cookies[0].Name != "sid" {
+		t.Errorf("did not get expected cookies: %v", cookies)
+	}
+	loc, _ = url.Parse("https://example.org/")
+	if cookies := jar.Cookies(loc); len(cookies) != 0 {
+		t.Errorf("did not expect cookies of other hosts: %v", cookies)
+	}
+
+	var err = NewNetscapeLoader(New(), path).Load("old.example.net")
+	var notFound *NoCookiesFoundError
+	if !errors.As(err, &notFound) {
+		t.Errorf("did not get expected NoCookiesFoundError for expired cookies: %v", err)
+	}
+}
+
+func TestExportNetscape_PlainJar(t *testing.T) {
+	var jar, _ = cookiejar.New(nil)
+	var loc, _ = url.Parse("https://www.example.com/")
+	jar.SetCookies(loc, []*http.Cookie{{Name: "sid", Value: "abc"}})
+
+	var content bytes.Buffer
+	if err := ExportNetscape(&content, jar, loc); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(content.String(), "\nwww.example.com\tFALSE\t/\tTRUE\t0\tsid\tabc\n") {
+		t.Errorf("did not get expected cookies.txt:\n%s", content.String())
+	}
+
+	var err = ExportNetscape(&content, jar)
+	var cookieErr *CookieError
+	if !errors.As(err, &cookieErr) {
+		t.Errorf("did not get expected CookieError without locations: %v", err)
+	}
+}
+
+func TestExportNetscape_PersistentJar(t *testing.T) {
+	var jar, err = NewPersistent(filepath.Join(t.TempDir(), "jar.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var expires = time.Unix(4102444800, 0)
+	var loc, _ = url.Parse("https://www.example.com/app/page")
+	jar.SetCookies(loc, []*http.Cookie{
+		{Name: "bad:name", Value: "renamed"},
+		{Name: "sid", Value: "abc", Domain: "example.com", Path: "/", Expires: expires, Secure: true, HttpOnly: true},
+	})
+	var other, _ = url.Parse("http://other.org/")
+	jar.SetCookies(other, []*http.Cookie{{Name: "other", Value: "x"}})
+	// saving right away also keeps the background save out of the temporary directory
+	if err = jar.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	var content bytes.Buffer
+	if err = ExportNetscape(&content, jar, loc); err != nil {
+		t.Fatal(err)
+	}
+	var want = netscapeHeader + "\n# https://curl.se/docs/http-cookies.html\n\n" +
+		"#HttpOnly_.example.com\tTRUE\t/\tTRUE\t4102444800\tsid\tabc\n" +
+		"www.example.com\tFALSE\t/app\tFALSE\t0\tbad:name\trenamed\n"
+	if content.String() != want {
+		t.Errorf("did not get expected cookies.txt:\n%s", content.String())
+	}
+}
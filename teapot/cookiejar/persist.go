@@ -0,0 +1,344 @@
+package cookiejar
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"gadget/settings"
+	"gadget/storage"
+)
+
+// saveDelay is how long changes are collected before they are written.
+const saveDelay = time.Second
+
+// persistentJar is a cookieContainer that survives restarts by writing its
+// cookies to a file shortly after they change.
+//
+// The std lib jar has no way to list what it holds, so every cookie it
+// accepts is also recorded here; the records are what get written to disk
+// and replayed into the jar when the file is loaded again. Session cookies,
+// the ones without an expiry, are kept as well since that is usually where
+// a login lives.
+//
+// Changes are written in the background, at most once per saveDelay, so
+// setting cookies never waits on the disk; call Save before exiting to
+// write the latest changes.
+type persistentJar struct {
+	*cookieContainer
+
+	path    string
+	mu      sync.Mutex
+	records map[string]*storedCookie
+	pending *time.Timer
+
+	// saving keeps writes in the order their contents were taken
+	saving sync.Mutex
+}
+
+// storedCookie is a cookie as it was set, along with the location it was set for.
+type storedCookie struct {
+	URL      string        `json:"url"`
+	Name     string        `json:"name"`
+	Value    string        `json:"value"`
+	Path     string        `json:"path,omitempty"`
+	Domain   string        `json:"domain,omitempty"`
+	Expires  int64         `json:"expires,omitempty"` // unix seconds, zero for session cookies
+	Secure   bool          `json:"secure,omitempty"`
+	HttpOnly bool          `json:"http_only,omitempty"`
+	SameSite http.SameSite `json:"same_site,omitempty"`
+}
+
+// jarFile is the on-disk format of a persistentJar.
+type jarFile struct {
+	Cookies    []*storedCookie   `json:"cookies"`
+	NameMap    map[string]string `json:"name_map,omitempty"`
+	NameLookup map[string]string `json:"name_lookup,omitempty"`
+}
+
+// NewPersistent constructs a jar that is stored at path, loading any cookies
+// that were saved there before; expired cookies are dropped on load.
+func NewPersistent(path string, options ...Option) (*persistentJar, error) {
+	var jar = &persistentJar{
+		cookieContainer: New(options...),
+		path:            path,
+		records:         make(map[string]*storedCookie),
+	}
+	if err := jar.load(); err != nil {
+		return nil, err
+	}
+	return jar, nil
+}
+
+// NewUserPersistent constructs a persistent jar named name in the user cache
+// directory of the namespace.
+func NewUserPersistent(dirs settings.UserDirs, name string, options ...Option) (*persistentJar, error) {
+	return NewPersistent(filepath.Join(dirs.Cache(), "teapot", "cookies", name+".json"), options...)
+}
+
+// Path returns the location of the file the jar is stored in.
+func (jar *persistentJar) Path() string {
+	return jar.path
+}
+
+// SetCookies func of the std lib interface; the jar is saved shortly after.
+func (jar *persistentJar) SetCookies(uri *url.URL, cookies []*http.Cookie) {
+	jar.mu.Lock()
+	defer jar.mu.Unlock()
+
+	// cookies are renamed in place, so they are recorded by the name the
+	// std lib jar knows them by
+	jar.cookieContainer.SetCookies(uri, cookies)
+	if uri == nil || uri.Scheme == "" || uri.Host == "" {
+		return
+	}
+
+	var now = time.Now()
+	var changed bool
+	for _, cookie := range cookies {
+		var key, record = newStoredCookie(uri, cookie, now)
+		if record == nil {
+			if _, found := jar.records[key]; found {
+				delete(jar.records, key)
+				changed = true
+			}
+			continue
+		}
+		// the std lib jar silently drops cookies it considers invalid
+		if !jar.accepted(record) {
+			continue
+		}
+		jar.records[key] = record
+		changed = true
+	}
+
+	if changed && jar.pending == nil {
+		jar.pending = time.AfterFunc(saveDelay, func() {
+			if err := jar.Save(); err != nil {
+				jar.log.Errorf("saving cookies to %s: %v", jar.path, err)
+				jar.errhandler(err)
+			}
+		})
+	}
+}
+
+// accepted reports whether the std lib jar holds the cookie of the record.
+func (jar *persistentJar) accepted(record *storedCookie) bool {
+	var loc, err = url.Parse(record.URL)
+	if err != nil {
+		return false
+	}
+	if record.Secure {
+		loc.Scheme = "https"
+	}
+	if strings.HasPrefix(record.Path, "/") {
+		loc.Path = record.Path
+	}
+	for _, cookie := range jar.data.Cookies(loc) {
+		if cookie.Name == record.Name && cookie.Value == record.Value {
+			return true
+		}
+	}
+	return false
+}
+
+// Cookies func of the std lib interface.
+func (jar *persistentJar) Cookies(uri *url.URL) []*http.Cookie {
+	jar.mu.Lock()
+	defer jar.mu.Unlock()
+	return jar.cookieContainer.Cookies(uri)
+}
+
+// Save writes the cookies to disk right away, including changes that are
+// still waiting to be written in the background.
+func (jar *persistentJar) Save() error {
+	jar.saving.Lock()
+	defer jar.saving.Unlock()
+
+	jar.mu.Lock()
+	if jar.pending != nil {
+		jar.pending.Stop()
+		jar.pending = nil
+	}
+	var content, err = jar.marshal()
+	jar.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return writeFile(jar.path, content)
+}
+
+func (jar *persistentJar) load() error {
+	var content, err = os.ReadFile(jar.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	var file jarFile
+	if err = json.Unmarshal(content, &file); err != nil {
+		return &CookieError{Message: fmt.Sprintf("invalid cookie file %s: %v", jar.path, err)}
+	}
+
+	var now = time.Now()
+	var dropped int
+	var names = make(map[string]bool, len(file.Cookies))
+	for _, record := range file.Cookies {
+		var uri *url.URL
+		if uri, err = url.Parse(record.URL); err != nil || uri.Host == "" || record.expired(now) {
+			dropped++
+			continue
+		}
+		var key, _ = newStoredCookie(uri, record.cookie(), now)
+		jar.records[key] = record
+		names[record.Name] = true
+		// the names were already cleaned when the cookies were first set
+		jar.data.SetCookies(uri, []*http.Cookie{record.cookie()})
+	}
+
+	// only the renames of cookies that are still around are worth keeping
+	for name, original := range file.NameMap {
+		if !names[name] {
+			dropped++
+			continue
+		}
+		jar.nameMap[name] = original
+		if lookup, ok := file.NameLookup[original]; ok && lookup == name {
+			jar.nameLookup[original] = name
+		}
+	}
+
+	if dropped != 0 {
+		jar.log.Debugf("dropped %d expired entries from %s", dropped, jar.path)
+		return jar.Save()
+	}
+	return nil
+}
+
+// marshal returns the contents of the file; the jar must be locked.
+func (jar *persistentJar) marshal() ([]byte, error) {
+	var file = jarFile{
+		Cookies:    make([]*storedCookie, 0, len(jar.records)),
+		NameMap:    jar.nameMap,
+		NameLookup: jar.nameLookup,
+	}
+	var now = time.Now()
+	for _, record := range jar.records {
+		if !record.expired(now) {
+			file.Cookies = append(file.Cookies, record)
+		}
+	}
+	return json.MarshalIndent(&file, "", "  ")
+}
+
+// writeFile writes to a temporary file first so a crash never leaves a
+// partial file behind.
+func writeFile(name string, content []byte) error {
+	var err error
+	var tmp *os.File
+
+	var dir = filepath.Dir(name)
+	if err = os.MkdirAll(dir, storage.MinDirPermission); err != nil {
+		return err
+	}
+	if tmp, err = os.CreateTemp(dir, ".cookies-*"); err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err = tmp.Write(content); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	if err = os.Chmod(tmp.Name(), storage.MinFilePermission); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), name)
+}
+
+// newStoredCookie returns the key a cookie is stored under, which follows
+// how the std lib jar tells cookies apart, and the record to keep; the
+// record is nil if the cookie deletes or expires the stored one.
+func newStoredCookie(uri *url.URL, cookie *http.Cookie, now time.Time) (string, *storedCookie) {
+	var domain = strings.ToLower(strings.TrimPrefix(cookie.Domain, "."))
+	if domain == "" {
+		domain = strings.ToLower(uri.Hostname())
+	}
+	var cookiePath = cookie.Path
+	if !strings.HasPrefix(cookiePath, "/") {
+		cookiePath = defaultPath(uri.Path)
+	}
+	var key = domain + ";" + cookiePath + ";" + cookie.Name
+
+	var record = &storedCookie{
+		URL:      (&url.URL{Scheme: uri.Scheme, Host: uri.Host, Path: uri.Path}).String(),
+		Name:     cookie.Name,
+		Value:    cookie.Value,
+		Path:     cookie.Path,
+		Domain:   cookie.Domain,
+		Secure:   cookie.Secure,
+		HttpOnly: cookie.HttpOnly,
+		SameSite: cookie.SameSite,
+	}
+	switch {
+	case cookie.MaxAge < 0:
+		return key, nil
+	case cookie.MaxAge > 0:
+		record.Expires = now.Add(time.Duration(cookie.MaxAge) * time.Second).Unix()
+	case !cookie.Expires.IsZero():
+		record.Expires = cookie.Expires.Unix()
+	}
+	if record.expired(now) {
+		return key, nil
+	}
+	return key, record
+}
+
+func (record *storedCookie) expired(now time.Time) bool {
+	return record.Expires != 0 && record.Expires <= now.Unix()
+}
+
+func (record *storedCookie) expires() time.Time {
+	if record.Expires == 0 {
+		return time.Time{}
+	}
+	return time.Unix(record.Expires, 0)
+}
+
+func (record *storedCookie) cookie() *http.Cookie {
+	return &http.Cookie{
+		Name:     record.Name,
+		Value:    record.Value,
+		Path:     record.Path,
+		Domain:   record.Domain,
+		Expires:  record.expires(),
+		Secure:   record.Secure,
+		HttpOnly: record.HttpOnly,
+		SameSite: record.SameSite,
+	}
+}
+
+// defaultPath is the path a cookie without a Path attribute applies to,
+// as described in RFC 6265 section 5.1.4.
+func defaultPath(uriPath string) string {
+	if uriPath == "" || uriPath[0] != '/' {
+		return "/"
+	}
+	return path.Dir(uriPath)
+}
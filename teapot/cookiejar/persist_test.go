@@ -0,0 +1,79 @@
+package cookiejar
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPersistentJar_RoundTrip(t *testing.T) {
+	var path = filepath.Join(t.TempDir(), "cookies", "jar.json")
+	var jar, err = NewPersistent(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var loc, _ = url.Parse("https://example.com/")
+	jar.SetCookies(loc, []*http.Cookie{
+		{Name: "bad:name", Value: "renamed"},
+		{Name: "sid", Value: "abc", MaxAge: 3600},
+		{Name: "short", Value: "lived", Expires: time.Now().Add(time.Second)},
+	})
+	// rejected by the std lib jar, so it must not be persisted either
+	jar.SetCookies(loc, []*http.Cookie{{Name: "foreign", Value: "x", Domain: "other.org"}})
+	if err = jar.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	var info os.FileInfo
+	if info, err = os.Stat(path); err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("did not get expected permissions %v", info.Mode().Perm())
+	}
+
+	time.Sleep(1100 * time.Millisecond)
+	var restored *persistentJar
+	if restored, err = NewPersistent(path); err != nil {
+		t.Fatal(err)
+	}
+	var found = make(map[string]string)
+	for _, cookie := range restored.Cookies(loc) {
+		found[cookie.Name] = cookie.Value
+	}
+	if found["bad:name"] != "renamed" || found["sid"] != "abc" || len(found) != 2 {
+		t.Errorf("did not get expected cookies: %v", found)
+	}
+
+	// the expired cookie and its rename are gone from the file as well
+	var content, _ = os.ReadFile(path)
+	var file jarFile
+	if err = json.Unmarshal(content, &file); err != nil {
+		t.Fatal(err)
+	}
+	if len(file.Cookies) != 2 || len(file.NameMap) != 2 || len(file.NameLookup) != 2 {
+		t.Errorf("did not get expected file: %d cookies, %d names", len(file.Cookies), len(file.NameMap))
+	}
+	for name, original := range file.NameMap {
+		if file.NameLookup[original] != name || restored.nameLookup[original] != name {
+			t.Errorf("did not restore the rename of '%s'", original)
+		}
+	}
+
+	// setting a known name again reuses its rename
+	restored.SetCookies(loc, []*http.Cookie{{Name: "sid", MaxAge: -1}})
+	if err = restored.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if restored, err = NewPersistent(path); err != nil {
+		t.Fatal(err)
+	}
+	if cookies := restored.Cookies(loc); len(cookies) != 1 || cookies[0].Name != "bad:name" {
+		t.Errorf("did not get expected cookies after deletion: %v", cookies)
+	}
+}
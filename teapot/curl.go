@@ -0,0 +1,177 @@
+package teapot
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Curl returns a curl command line that repeats the request of the Result,
+// including its headers and the body if it can be replayed; a body that
+// cannot is read from stdin instead.
+//
+// The Client adds the cookies of its jar to the Request of the Result, so
+// they are included unless a middleware such as HeaderMiddleware cloned the
+// request on its way to the Client; Session.Curl takes them from the jar.
+func (res *Result) Curl() string {
+	if res == nil || res.Request == nil {
+		return ""
+	}
+	return curlCommand(res.Request, nil)
+}
+
+// Curl returns a curl command line for the request without sending it,
+// adding the cookies the jar of the Session holds for the location.
+//
+// The Authenticator of the Session is applied as well, which may require
+// fetching a token first.
+func (session *teacup) Curl() (string, error) {
+	if session.err != nil {
+		return "", session.err
+	}
+	var req, err = session.prepare(context.Background())
+	if err != nil {
+		return "", err
+	}
+	if session.auth != nil {
+		if err = session.auth.Authenticate(req); err != nil {
+			return "", err
+		}
+	}
+	var cookies []*http.Cookie
+	if session.jar != nil && req.Header.Get("Cookie") == "" {
+		cookies = session.jar.Cookies(req.URL)
+	}
+	return curlCommand(req, cookies), nil
+}
+
+func curlCommand(req *http.Request, cookies []*http.Cookie) string {
+	var args = []string{"curl"}
+	var content, replayable = requestBody(req)
+	switch req.Method {
+	case "", http.MethodGet:
+		// curl would switch to POST for the data
+		if !replayable || len(content) != 0 {
+			args = append(args, "-X", http.MethodGet)
+		}
+	case http.MethodHead:
+		args = append(args, "--head")
+	default:
+		args = append(args, "-X", req.Method)
+	}
+	args = append(args, shellQuote(req.URL.String()))
+
+	var names = make([]string, 0, len(req.Header))
+	for name := range req.Header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, value := range req.Header[name] {
+			args = append(args, "-H", shellQuote(name+": "+value))
+		}
+	}
+	if req.Host != "" && req.Host != req.URL.Host {
+		args = append(args, "-H", shellQuote("Host: "+req.Host))
+	}
+	if len(cookies) != 0 {
+		var pairs = make([]string, 0, len(cookies))
+		for _, cookie := range cookies {
+			pairs = append(pairs, cookie.Name+"="+cookie.Value)
+		}
+		args = append(args, "--cookie", shellQuote(strings.Join(pairs, "; ")))
+	}
+
+	if replayable && len(content) != 0 {
+		if utf8.Valid(content) {
+			args = append(args, "--data-binary", shellQuote(string(content)))
+		} else {
+			args = append(args, "--data-binary", "@-")
+		}
+	} else if !replayable {
+		args = append(args, "--data-binary", "@-")
+	}
+
+	return strings.Join(args, " ")
+}
+
+// requestBody returns the body of a request without consuming it;
+// replayable is false if there is a body that can only be read once.
+func requestBody(req *http.Request) (content []byte, replayable bool) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, true
+	}
+	if req.GetBody == nil {
+		return nil, false
+	}
+	var body, err = req.GetBody()
+	if err != nil {
+		return nil, false
+	}
+	defer body.Close()
+	if content, err = io.ReadAll(body); err != nil {
+		return nil, false
+	}
+	return content, true
+}
+
+// shellQuote wraps s in single quotes for POSIX shells.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// RawRequest returns the request in HTTP/1.1 wire format as it is written
+// by the transport; the body is only included if it can be replayed.
+func (res *Result) RawRequest() ([]byte, error) {
+	if res == nil || res.Request == nil {
+		return nil, nil
+	}
+	var req = res.Request.Clone(context.Background())
+	var content, replayable = requestBody(res.Request)
+	if replayable && content != nil {
+		req.Body = io.NopCloser(bytes.NewReader(content))
+	}
+	return httputil.DumpRequestOut(req, replayable)
+}
+
+// RawResponse returns the response in HTTP/1.1 wire format; the body is
+// only included if it was buffered.
+func (res *Result) RawResponse() ([]byte, error) {
+	if res == nil || res.Response == nil {
+		return nil, nil
+	}
+	var resp = *res.Response
+	if res.Body == nil {
+		return httputil.DumpResponse(&resp, false)
+	}
+	// the body has been decompressed and read already
+	resp.Body = io.NopCloser(bytes.NewReader(res.Body))
+	resp.ContentLength = int64(len(res.Body))
+	resp.TransferEncoding = nil
+	resp.Header = resp.Header.Clone()
+	resp.Header.Del("Content-Encoding")
+	return httputil.DumpResponse(&resp, true)
+}
+
+// DumpRaw returns both the request and the response in wire format.
+func (res *Result) DumpRaw() (string, error) {
+	var dump strings.Builder
+	var raw, err = res.RawRequest()
+	if err != nil {
+		return "", err
+	}
+	dump.Write(raw)
+	if raw, err = res.RawResponse(); err != nil {
+		return "", err
+	}
+	if len(raw) != 0 {
+		dump.WriteString("\n\n")
+		dump.Write(raw)
+	}
+	return dump.String(), nil
+}
@@ -0,0 +1,191 @@
+package teapot
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"mime"
+	"strings"
+
+	"golang.org/x/net/html/charset"
+)
+
+// ExpectStatus fails the Result with a StatusCodeError unless the response
+// has the given status. Like all expectations it does nothing if the
+// Result already failed, so they can be chained before decoding:
+//
+//	var err = result.ExpectSuccess().ExpectContentType("application/json").DecodeJSON(&dest)
+func (res *Result) ExpectStatus(code int) *Result {
+	if res.Error == nil && res.StatusCode() != code {
+		res.Error = &StatusCodeError{StatusCode: res.StatusCode(), Expected: code}
+	}
+	return res
+}
+
+// ExpectStatusRange fails the Result with a StatusCodeError unless the
+// response status is between min and max inclusive.
+func (res *Result) ExpectStatusRange(min int, max int) *Result {
+	if res.Error == nil && (res.StatusCode() < min || res.StatusCode() > max) {
+		res.Error = &StatusCodeError{StatusCode: res.StatusCode(), Expected: min, ExpectedMax: max}
+	}
+	return res
+}
+
+// ExpectSuccess fails the Result unless the response status is 2xx.
+func (res *Result) ExpectSuccess() *Result {
+	return res.ExpectStatusRange(200, 299)
+}
+
+// ExpectContentType fails the Result with a ContentTypeError unless the
+// media type of the response matches one of the given ones. Parameters
+// such as charset are ignored and `*` may be used as the subtype, e.g.
+// `text/*`; a structured syntax suffix such as `+json` also matches.
+func (res *Result) ExpectContentType(mediatypes ...string) *Result {
+	if res.Error != nil {
+		return res
+	}
+
+	var actual = res.mediatype()
+	for _, expected := range mediatypes {
+		if mediatypeMatches(actual, strings.ToLower(expected)) {
+			return res
+		}
+	}
+
+	var contentType string
+	if res.Response != nil {
+		contentType = res.Response.Header.Get("Content-Type")
+	}
+	res.Error = &ContentTypeError{ContentType: contentType, Expected: strings.Join(mediatypes, ", ")}
+	return res
+}
+
+// ExpectMaxBodySize fails the Result with a BodyTooLargeError if the body
+// is larger than limit bytes; see Requestor.MaxBodySize to avoid reading
+// such bodies in the first place.
+func (res *Result) ExpectMaxBodySize(limit int64) *Result {
+	if res.Error == nil && int64(len(res.Body)) > limit {
+		res.Error = &BodyTooLargeError{Limit: limit, Size: int64(len(res.Body))}
+	}
+	return res
+}
+
+func (res *Result) mediatype() string {
+	if res == nil || res.Response == nil {
+		return ""
+	}
+	var mediatype, _, err = mime.ParseMediaType(res.Response.Header.Get("Content-Type"))
+	if err != nil {
+		return ""
+	}
+	return mediatype
+}
+
+func mediatypeMatches(actual string, expected string) bool {
+	if actual == expected {
+		return true
+	}
+	var actualType, actualSub, _ = strings.Cut(actual, "/")
+	var expectedType, expectedSub, _ = strings.Cut(expected, "/")
+	if actualType != expectedType {
+		return false
+	}
+	if expectedSub == "*" {
+		return true
+	}
+	// e.g. `application/problem+json` satisfies `application/json`
+	return strings.HasSuffix(actualSub, "+"+expectedSub)
+}
+
+// Reader returns the body converted to UTF-8 according to the charset
+// parameter of the response `Content-Type`.
+func (res *Result) Reader() (io.Reader, error) {
+	if res.Error != nil {
+		return nil, res.Error
+	}
+
+	var body io.Reader = bytes.NewReader(res.Body)
+	var label = res.charset()
+	if label == "" || label == "utf-8" || label == "utf8" {
+		return body, nil
+	}
+	return charset.NewReaderLabel(label, body)
+}
+
+// charset returns the lower case charset of the Content-Type, if any.
+func (res *Result) charset() string {
+	if res.Response == nil {
+		return ""
+	}
+	var _, params, err = mime.ParseMediaType(res.Response.Header.Get("Content-Type"))
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(params["charset"])
+}
+
+// DecodeJSON unmarshals the body into dest; unlike JSON it returns the
+// error of the Result first and honors the response charset.
+func (res *Result) DecodeJSON(dest any) error {
+	var reader, err = res.Reader()
+	if err != nil {
+		return err
+	}
+	return json.NewDecoder(reader).Decode(dest)
+}
+
+// DecodeXML unmarshals the body into dest, honoring the response charset
+// or, without one, the encoding declared by the document itself.
+func (res *Result) DecodeXML(dest any) error {
+	var reader, err = res.Reader()
+	if err != nil {
+		return err
+	}
+	var decoder = xml.NewDecoder(reader)
+	decoder.CharsetReader = charset.NewReaderLabel
+	if res.charset() != "" {
+		// the body is UTF-8 already, whatever the declaration says
+		decoder.CharsetReader = func(_ string, input io.Reader) (io.Reader, error) {
+			return input, nil
+		}
+	}
+	return decoder.Decode(dest)
+}
+
+// DecodeCSV reads all records of the body; configure may adjust the
+// csv.Reader, e.g. to change the separator, and can be nil.
+func (res *Result) DecodeCSV(configure func(reader *csv.Reader)) ([][]string, error) {
+	var body, err = res.Reader()
+	if err != nil {
+		return nil, err
+	}
+	var reader = csv.NewReader(body)
+	if configure != nil {
+		configure(reader)
+	}
+	return reader.ReadAll()
+}
+
+// DecodeNDJSON calls fn with every line of a newline delimited JSON body,
+// stopping at the first error returned by fn.
+func (res *Result) DecodeNDJSON(fn func(line json.RawMessage) error) error {
+	var reader, err = res.Reader()
+	if err != nil {
+		return err
+	}
+
+	var decoder = json.NewDecoder(reader)
+	for {
+		var line json.RawMessage
+		if err = decoder.Decode(&line); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if err = fn(line); err != nil {
+			return err
+		}
+	}
+}
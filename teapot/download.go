@@ -0,0 +1,378 @@
+package teapot
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"gadget/logging"
+	"gadget/storage"
+)
+
+// DownloadProgress is reported periodically while a download is running.
+type DownloadProgress struct {
+	Path string
+
+	// Written is the number of bytes of the file on disk so far,
+	// including any bytes kept from a previous partial download.
+	Written int64
+
+	// Resumed is the number of bytes that were kept from a previous run.
+	Resumed int64
+
+	// Total is the expected size of the file or -1 when unknown.
+	Total int64
+}
+
+// DownloadOption configures a single call to Download.
+type DownloadOption func(dl *download)
+
+// DownloadProgressFunc receives progress events; it should return quickly.
+func DownloadProgressFunc(fn func(progress DownloadProgress)) DownloadOption {
+	return func(dl *download) {
+		dl.progress = fn
+	}
+}
+
+// DownloadLogger logs progress events at the info level.
+func DownloadLogger(log logging.Logger) DownloadOption {
+	return func(dl *download) {
+		dl.log = log
+	}
+}
+
+// DownloadInterval sets the minimum time in between progress events.
+func DownloadInterval(interval time.Duration) DownloadOption {
+	return func(dl *download) {
+		dl.interval = interval
+	}
+}
+
+// DownloadChecksum verifies the finished file against a hex encoded SHA-256 digest.
+func DownloadChecksum(sha256sum string) DownloadOption {
+	return func(dl *download) {
+		dl.checksum = strings.ToLower(strings.TrimSpace(sha256sum))
+	}
+}
+
+// DownloadFileMode sets the permissions of the finished file.
+func DownloadFileMode(mode os.FileMode) DownloadOption {
+	return func(dl *download) {
+		dl.mode = mode
+	}
+}
+
+// DownloadNoResume always starts over instead of resuming a partial file.
+func DownloadNoResume(dl *download) {
+	dl.noResume = true
+}
+
+// downloadState is stored next to a partial file so that a later
+// run can verify it is resuming the same remote content.
+type downloadState struct {
+	URL          string `json:"url"`
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+	Total        int64  `json:"total"`
+}
+
+type download struct {
+	log      logging.Logger
+	progress func(progress DownloadProgress)
+	interval time.Duration
+	checksum string
+	mode     os.FileMode
+	noResume bool
+
+	dest    string
+	partial string
+	meta    string
+}
+
+// Download saves the resource at loc to dest using the Session.
+//
+// The content is written to `<dest>.part` and only renamed to dest once it
+// is complete (and matches the checksum, if one was given), so dest never
+// contains a partial file. When a previous run left a partial file behind,
+// the download is resumed with a `Range` request guarded by `If-Range`
+// using the ETag or Last-Modified validator recorded at the time; if the
+// remote content changed in the meantime the server sends the whole file
+// again and the download starts over.
+func Download(ctx context.Context, session Session, loc string, dest string, options ...DownloadOption) (*DownloadProgress, error) {
+	var dl = &download{
+		interval: time.Second,
+		mode:     storage.MinFilePermission,
+		dest:     dest,
+		partial:  dest + ".part",
+		meta:     dest + ".part.json",
+	}
+	for _, option := range options {
+		option(dl)
+	}
+
+	return dl.run(ctx, session, loc)
+}
+
+func (dl *download) run(ctx context.Context, session Session, loc string) (*DownloadProgress, error) {
+	var err error
+	var state downloadState
+	var offset int64
+
+	if dl.dest == "" {
+		return nil, &DownloadError{Path: dl.dest, Reason: "empty destination path"}
+	}
+	if err = os.MkdirAll(filepath.Dir(dl.dest), storage.MinDirPermission); err != nil {
+		return nil, err
+	}
+
+	if !dl.noResume {
+		state, offset = dl.resumable(loc)
+	}
+	if offset == 0 {
+		dl.cleanup()
+	}
+
+	var ranged = session
+	if offset > 0 {
+		var validator = state.ETag
+		if validator == "" {
+			validator = state.LastModified
+		}
+		ranged = session.Mutate().OnRequest(func(req *http.Request) error {
+			req.Header.Set("Range", "bytes="+strconv.FormatInt(offset, 10)+"-")
+			req.Header.Set("If-Range", validator)
+			return nil
+		}).Make()
+	}
+
+	var stream = ranged.URLstring(loc).Stream(ctx, http.MethodGet)
+	defer stream.Close()
+	if stream.Error != nil {
+		return nil, stream.Error
+	}
+
+	var resp = stream.Response
+	switch {
+	case resp.StatusCode == http.StatusPartialContent && offset > 0:
+		// resuming where the previous run left off
+		if start := contentRangeStart(resp.Header.Get("Content-Range")); start != offset {
+			return nil, &DownloadError{Path: dl.dest, Reason: "unexpected Content-Range: " + resp.Header.Get("Content-Range")}
+		}
+	case resp.StatusCode == http.StatusRequestedRangeNotSatisfiable && offset > 0 && offset == state.Total:
+		// the previous run was interrupted after the last byte was written
+		return dl.finish(offset, offset, state.Total)
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		offset = 0
+		state = downloadState{
+			URL:          loc,
+			ETag:         resp.Header.Get("ETag"),
+			LastModified: resp.Header.Get("Last-Modified"),
+			Total:        resp.ContentLength,
+		}
+	default:
+		return nil, &StatusCodeError{StatusCode: resp.StatusCode, Expected: http.StatusOK}
+	}
+
+	if offset == 0 {
+		dl.cleanup()
+		if err = dl.saveState(state); err != nil {
+			return nil, err
+		}
+	}
+
+	var written int64
+	if written, err = dl.write(stream, offset, state.Total); err != nil {
+		return nil, err
+	}
+
+	return dl.finish(offset+written, offset, state.Total)
+}
+
+// resumable returns the recorded state and the size of the partial file
+// if the previous run for the same URL can be continued.
+func (dl *download) resumable(loc string) (downloadState, int64) {
+	var state downloadState
+	var content, err = os.ReadFile(dl.meta)
+	if err != nil {
+		return state, 0
+	}
+	if err = json.Unmarshal(content, &state); err != nil || state.URL != loc {
+		return state, 0
+	}
+	if state.ETag == "" && state.LastModified == "" {
+		// without a validator there is no way to know the content is unchanged
+		return state, 0
+	}
+	if strings.HasPrefix(state.ETag, "W/") && state.LastModified == "" {
+		// weak validators cannot be used with If-Range
+		return state, 0
+	}
+	if strings.HasPrefix(state.ETag, "W/") {
+		state.ETag = ""
+	}
+
+	var info os.FileInfo
+	if info, err = os.Stat(dl.partial); err != nil || info.Size() == 0 {
+		return state, 0
+	}
+	if state.Total >= 0 && info.Size() > state.Total {
+		return state, 0
+	}
+	return state, info.Size()
+}
+
+func (dl *download) saveState(state downloadState) error {
+	var content, err = json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(dl.meta, content, storage.MinFilePermission)
+}
+
+func (dl *download) cleanup() {
+	_ = os.Remove(dl.partial)
+	_ = os.Remove(dl.meta)
+}
+
+// write appends the body to the partial file starting at offset.
+func (dl *download) write(body io.Reader, offset int64, total int64) (int64, error) {
+	var err error
+	var file *os.File
+
+	var flags = os.O_WRONLY | os.O_CREATE
+	if offset == 0 {
+		flags |= os.O_TRUNC
+	} else {
+		flags |= os.O_APPEND
+	}
+	if file, err = os.OpenFile(dl.partial, flags, storage.MinFilePermission); err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	var reporter = &progressWriter{dl: dl, resumed: offset, written: offset, total: total}
+	var written int64
+	if written, err = io.Copy(io.MultiWriter(file, reporter), body); err != nil {
+		return written, err
+	}
+	reporter.report(true)
+
+	if err = file.Sync(); err != nil {
+		return written, err
+	}
+	return written, file.Close()
+}
+
+// finish verifies the partial file and moves it into place.
+func (dl *download) finish(size int64, resumed int64, total int64) (*DownloadProgress, error) {
+	var err error
+	var progress = &DownloadProgress{Path: dl.dest, Written: size, Resumed: resumed, Total: total}
+
+	if total >= 0 && size != total {
+		return progress, &DownloadError{Path: dl.dest, Reason: fmt.Sprintf("incomplete download: %d of %d bytes", size, total)}
+	}
+
+	if dl.checksum != "" {
+		var actual string
+		if actual, err = sha256File(dl.partial); err != nil {
+			return progress, err
+		}
+		if actual != dl.checksum {
+			dl.cleanup()
+			return progress, &ChecksumError{Path: dl.dest, Expected: dl.checksum, Actual: actual}
+		}
+	}
+
+	if err = os.Chmod(dl.partial, dl.mode); err != nil {
+		return progress, err
+	}
+	if err = os.Rename(dl.partial, dl.dest); err != nil {
+		return progress, err
+	}
+	if err = os.Remove(dl.meta); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return progress, err
+	}
+
+	return progress, nil
+}
+
+func sha256File(path string) (string, error) {
+	var err error
+	var file *os.File
+	var digest = sha256.New()
+
+	if file, err = os.Open(filepath.Clean(path)); err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	if _, err = io.Copy(digest, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(digest.Sum(nil)), nil
+}
+
+// contentRangeStart extracts the first byte position of a `Content-Range`
+// header such as `bytes 200-1023/1024`, returning -1 if it is malformed.
+func contentRangeStart(value string) int64 {
+	var spec = strings.TrimPrefix(strings.TrimSpace(value), "bytes ")
+	var dash = strings.IndexByte(spec, '-')
+	if dash < 1 {
+		return -1
+	}
+	var start, err = strconv.ParseInt(spec[:dash], 10, 64)
+	if err != nil {
+		return -1
+	}
+	return start
+}
+
+// progressWriter counts the bytes written and reports them at an interval.
+type progressWriter struct {
+	dl       *download
+	resumed  int64
+	written  int64
+	total    int64
+	reported time.Time
+}
+
+func (pw *progressWriter) Write(p []byte) (int, error) {
+	pw.written += int64(len(p))
+	pw.report(false)
+	return len(p), nil
+}
+
+func (pw *progressWriter) report(final bool) {
+	if pw.dl.progress == nil && pw.dl.log == nil {
+		return
+	}
+	var now = time.Now()
+	if !final && now.Sub(pw.reported) < pw.dl.interval {
+		return
+	}
+	pw.reported = now
+
+	var progress = DownloadProgress{Path: pw.dl.dest, Written: pw.written, Resumed: pw.resumed, Total: pw.total}
+	if pw.dl.progress != nil {
+		pw.dl.progress(progress)
+	}
+	if pw.dl.log != nil {
+		pw.dl.log.Infow(
+			"download progress",
+			"path", progress.Path,
+			"written", progress.Written,
+			"resumed", progress.Resumed,
+			"total", progress.Total,
+		)
+	}
+}
@@ -3,6 +3,7 @@ package teapot
 import (
 	"fmt"
 	"net/url"
+	"time"
 )
 
 type UserAgentError struct {
@@ -16,12 +17,18 @@ func (e *UserAgentError) Error() string {
 type StatusCodeError struct {
 	StatusCode int
 	Expected   int
+
+	// ExpectedMax is set when any status from Expected up to it was acceptable.
+	ExpectedMax int
 }
 
 func (e *StatusCodeError) Error() string {
 	if e.Expected == 0 {
 		return fmt.Sprintf("unexpected HTTP status code: '%d'", e.StatusCode)
 	}
+	if e.ExpectedMax != 0 {
+		return fmt.Sprintf("unexpected HTTP status code: '%d' (expected '%d-%d')", e.StatusCode, e.Expected, e.ExpectedMax)
+	}
 	return fmt.Sprintf("unexpected HTTP status code: '%d' (expected '%d')", e.StatusCode, e.Expected)
 }
 
@@ -49,3 +56,101 @@ func (e *RequestFailedError) Error() string {
 	}
 	return fmt.Sprintf("%s %s %s", e.Status, e.Location.String(), e.Reason)
 }
+
+type BodyTooLargeError struct {
+	Limit int64
+	Size  int64
+}
+
+func (e *BodyTooLargeError) Error() string {
+	if e.Size <= 0 {
+		return fmt.Sprintf("HTTP response body exceeds limit of %d bytes", e.Limit)
+	}
+	return fmt.Sprintf("HTTP response body of %d bytes exceeds limit of %d bytes", e.Size, e.Limit)
+}
+
+type DownloadError struct {
+	Path   string
+	Reason string
+}
+
+func (e *DownloadError) Error() string {
+	return fmt.Sprintf("download failed for '%s': %s", e.Path, e.Reason)
+}
+
+type ChecksumError struct {
+	Path     string
+	Expected string
+	Actual   string
+}
+
+func (e *ChecksumError) Error() string {
+	return fmt.Sprintf("checksum mismatch for '%s': '%s' (expected '%s')", e.Path, e.Actual, e.Expected)
+}
+
+type AuthError struct {
+	Scheme string
+	Reason string
+}
+
+func (e *AuthError) Error() string {
+	return fmt.Sprintf("%s authentication failed: %s", e.Scheme, e.Reason)
+}
+
+type BatchError struct {
+	Index    int
+	Location *url.URL
+	Err      error
+}
+
+func (e *BatchError) Error() string {
+	if e.Location == nil {
+		return fmt.Sprintf("batch request %d failed: %v", e.Index, e.Err)
+	}
+	return fmt.Sprintf("batch request %d failed for '%s': %v", e.Index, e.Location.String(), e.Err)
+}
+
+func (e *BatchError) Unwrap() error {
+	return e.Err
+}
+
+type ProxyError struct {
+	Proxy  string
+	Reason string
+}
+
+func (e *ProxyError) Error() string {
+	if e.Proxy == "" {
+		return fmt.Sprintf("proxy unavailable: %s", e.Reason)
+	}
+	return fmt.Sprintf("invalid proxy '%s': %s", e.Proxy, e.Reason)
+}
+
+type TLSConfigError struct {
+	Path   string
+	Reason string
+}
+
+func (e *TLSConfigError) Error() string {
+	if e.Path == "" {
+		return fmt.Sprintf("TLS configuration error: %s", e.Reason)
+	}
+	return fmt.Sprintf("TLS configuration error for '%s': %s", e.Path, e.Reason)
+}
+
+type PublicKeyPinError struct {
+	Host string
+}
+
+func (e *PublicKeyPinError) Error() string {
+	return fmt.Sprintf("no pinned public key presented by '%s'", e.Host)
+}
+
+type CircuitOpenError struct {
+	Host  string
+	Until time.Time
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("circuit breaker open for '%s' until %s", e.Host, e.Until.Format(time.RFC3339))
+}
@@ -87,10 +87,16 @@ func (loader *ffxcookies) Load(hosts ...string) error {
 	if query = query.Find(&ffxcookies); query.Error != nil {
 		return query.Error
 	}
-	if len(ffxcookies) == 0 {
+	log.Debugf("%d cookies found", len(ffxcookies))
+
+	var sessionCookies []FirefoxSessionCookie
+	if sessionCookies, err = loader.core.sessionCookies(); err != nil {
+		// the session store only adds to the database, so it is not worth failing over
+		log.Warnf("skipping Firefox session cookies: %v", err)
+		err = nil
+	}
+	if len(ffxcookies) == 0 && len(sessionCookies) == 0 {
 		return &cookiejar.NoCookiesFoundError{Hosts: hosts}
-	} else {
-		log.Debugf("%d cookies found", len(ffxcookies))
 	}
 
 	var wrappedCookies = make(map[string]wrapperSlice)
@@ -99,7 +105,8 @@ func (loader *ffxcookies) Load(hosts ...string) error {
 		cookieCount++
 
 		// NOTE: cookiejar.Jar will ignore any cookies you try to set without a Scheme!
-		var loc = &url.URL{Host: ffxcookie.Host}
+		// It also rejects the leading dot of domain cookies as part of the host.
+		var loc = &url.URL{Host: strings.TrimPrefix(ffxcookie.Host, ".")}
 		if ffxcookie.Secure {
 			loc.Scheme = "https"
 		} else {
@@ -117,7 +124,7 @@ func (loader *ffxcookies) Load(hosts ...string) error {
 				Expires:  time.Unix(ffxcookie.Expiry, 0),
 				Secure:   ffxcookie.Secure,
 				HttpOnly: ffxcookie.HttpOnly,
-				SameSite: http.SameSite(ffxcookie.SameSite),
+				SameSite: sameSite(ffxcookie.SameSite),
 			},
 			valid: nil,
 		}
@@ -127,6 +134,23 @@ func (loader *ffxcookies) Load(hosts ...string) error {
 
 		wrappedCookies[ffxcookie.Host] = append(wrappedCookies[ffxcookie.Host], wrappedCookie)
 	}
+	// session cookies come last so they replace any older ones of the database
+	for _, sessioncookie := range sessionCookies {
+		if sessioncookie.Host == "" || !matchesHost(sessioncookie.Host, hosts) {
+			continue
+		}
+		cookieCount++
+
+		var loc = &url.URL{Scheme: "http", Host: strings.TrimPrefix(sessioncookie.Host, ".")}
+		if sessioncookie.Secure {
+			loc.Scheme = "https"
+		}
+		wrappedCookie = cookieWrapper{
+			host:       loc,
+			httpcookie: sessioncookie.httpCookie(),
+		}
+		wrappedCookies[sessioncookie.Host] = append(wrappedCookies[sessioncookie.Host], wrappedCookie)
+	}
 	if len(wrappedCookies) == 0 || cookieCount == 0 {
 		return new(cookiejar.NoCookiesFoundError)
 	} else {
@@ -210,3 +234,17 @@ func (loader *ffxcookies) Load(hosts ...string) error {
 
 	return err
 }
+
+// matchesHost mirrors the filter of the database query, which compares the
+// host column as it is.
+func matchesHost(host string, hosts []string) bool {
+	if len(hosts) == 0 {
+		return true
+	}
+	for _, candidate := range hosts {
+		if strings.TrimSpace(candidate) == host {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,57 @@
+package firefox
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"gadget/teapot/cookiejar"
+)
+
+func TestLoad_MergesSessionCookies(t *testing.T) {
+	var datapath = t.TempDir()
+	var profile = filepath.Join(datapath, "abcd.default-release")
+	if err := os.MkdirAll(filepath.Join(profile, "sessionstore-backups"), 0750); err != nil {
+		t.Fatal(err)
+	}
+	var ini = "[Profile0]\nName=default-release\nIsRelative=1\nPath=abcd.default-release\nDefault=1\n"
+	if err := os.WriteFile(filepath.Join(datapath, "profiles.ini"), []byte(ini), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	var db, err = gorm.Open(sqlite.Open(filepath.Join(profile, "cookies.sqlite")), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.Exec("CREATE TABLE moz_cookies (id INTEGER PRIMARY KEY, originAttributes TEXT, name TEXT, value TEXT, " +
+		"host TEXT, path TEXT, expiry INTEGER, lastAccessed INTEGER, creationTime INTEGER, isSecure INTEGER, " +
+		"isHttpOnly INTEGER, inBrowserElement INTEGER, sameSite INTEGER, rawSameSite INTEGER, schemeMap INTEGER)")
+	db.Exec("INSERT INTO moz_cookies VALUES (1, '', 'persistent', 'p', '.example.com', '/', 4102444800, 0, 0, 0, 0, 0, 0, 0, 0)")
+	var conn, _ = db.DB()
+	_ = conn.Close()
+
+	var store = []byte(`{"cookies":[{"host":".example.com","value":"s","path":"/","name":"session"},` +
+		`{"host":"other.org","value":"o","path":"/","name":"other"}]}`)
+	if err = os.WriteFile(filepath.Join(profile, "sessionstore-backups", "recovery.jsonlz4"), mozLz4(store), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	var jar = cookiejar.New()
+	if err = NewCookieLoader(jar, UseDataPath(datapath)).Load(".example.com"); err != nil {
+		t.Fatal(err)
+	}
+	var found = make(map[string]string)
+	for _, location := range []string{"http://www.example.com/", "http://other.org/"} {
+		var loc, _ = url.Parse(location)
+		for _, cookie := range jar.Cookies(loc) {
+			found[cookie.Name] = cookie.Value
+		}
+	}
+	if found["persistent"] != "p" || found["session"] != "s" || len(found) != 2 {
+		t.Errorf("did not get expected cookies: %v", found)
+	}
+}
@@ -14,12 +14,16 @@ func UseLogger(log logging.Logger) Option {
 	}
 }
 
+// UseDataPath sets the directory holding profiles.ini; the first one of
+// DataPaths if not set.
 func UseDataPath(path string) Option {
 	return func(core *firefoxCore) {
 		core.datapath = path
 	}
 }
 
+// UseProfile selects a profile by its name or directory; the default
+// profile if not set.
 func UseProfile(profile string) Option {
 	return func(core *firefoxCore) {
 		core.profile = profile
@@ -27,9 +31,10 @@ func UseProfile(profile string) Option {
 }
 
 type firefoxCore struct {
-	log      logging.Logger
-	datapath string
-	profile  string
+	log        logging.Logger
+	datapath   string
+	profile    string
+	profileDir string
 }
 
 // TODO: should this be exported?
@@ -54,18 +59,18 @@ func (core *firefoxCore) valid() error {
 	return err
 }
 
+// load finds the data path and the profile directory when they are not set,
+// using the default profile of profiles.ini if no profile is named.
 func (core *firefoxCore) load() error {
-	if core.datapath == "" {
-		// TODO: load from OS and return proper error
-		return new(EmptySqlitePathError)
+	if core.profileDir != "" {
+		return nil
 	}
-	if core.profile == "" {
-		// TODO: load from file and return proper error
-		return new(EmptySqlitePathError)
+	if err := core.loadDataPath(); err != nil {
+		return err
 	}
-	return nil
+	return core.loadProfile()
 }
 
 func (core *firefoxCore) cookieDBPath() string {
-	return "file:" + filepath.Join(core.datapath, "Profiles", core.profile, "cookies.sqlite") + "?immutable=1"
+	return "file:" + filepath.Join(core.profileDir, "cookies.sqlite") + "?immutable=1"
 }
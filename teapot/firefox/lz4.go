@@ -0,0 +1,115 @@
+package firefox
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+// mozLz4Magic starts every mozlz4 file, e.g. the session store.
+var mozLz4Magic = []byte("mozLz40\x00")
+
+type LZ4Error struct {
+	Reason string
+}
+
+func (e *LZ4Error) Error() string {
+	return "invalid mozlz4 data: " + e.Reason
+}
+
+// decodeMozLz4 decompresses Mozilla's LZ4 variant: the magic, the size of
+// the decompressed data as a little endian uint32 and a single LZ4 block.
+func decodeMozLz4(data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, mozLz4Magic) {
+		return nil, &LZ4Error{Reason: "missing mozLz40 header"}
+	}
+	data = data[len(mozLz4Magic):]
+	if len(data) < 4 {
+		return nil, &LZ4Error{Reason: "missing decompressed size"}
+	}
+	var size = binary.LittleEndian.Uint32(data)
+	return decodeLZ4Block(data[4:], int(size))
+}
+
+// decodeLZ4Block decompresses a raw LZ4 block into size bytes.
+//
+// A block is a series of sequences, each made of a token, literals that
+// are copied as they are and a match that copies earlier output again.
+// The high nibble of the token is the number of literals and the low one
+// the length of the match minus 4; a nibble of 15 continues with bytes
+// that are added to it until one is not 255. The match starts with a
+// little endian uint16 offset back into the output, and the last sequence
+// only has literals.
+//
+// https://github.com/lz4/lz4/blob/dev/doc/lz4_Block_format.md
+func decodeLZ4Block(src []byte, size int) ([]byte, error) {
+	// every byte of input produces at most 255 bytes of output, which
+	// keeps a corrupt size from allocating gigabytes up front
+	if size < 0 || size > 255*len(src) {
+		return nil, &LZ4Error{Reason: fmt.Sprintf("declared size %d too large for %d bytes of input", size, len(src))}
+	}
+	var dst = make([]byte, 0, size)
+	var pos int
+	for pos < len(src) {
+		var token = src[pos]
+		pos++
+
+		var literals, err = lz4Length(src, &pos, int(token>>4))
+		if err != nil {
+			return nil, err
+		}
+		if literals > len(src)-pos {
+			return nil, &LZ4Error{Reason: "literals beyond the end of the block"}
+		}
+		dst = append(dst, src[pos:pos+literals]...)
+		pos += literals
+		if pos == len(src) {
+			break
+		}
+
+		if len(src)-pos < 2 {
+			return nil, &LZ4Error{Reason: "truncated match offset"}
+		}
+		var offset = int(binary.LittleEndian.Uint16(src[pos:]))
+		pos += 2
+		if offset == 0 || offset > len(dst) {
+			return nil, &LZ4Error{Reason: fmt.Sprintf("match offset %d out of range", offset)}
+		}
+		var length int
+		if length, err = lz4Length(src, &pos, int(token&0x0f)); err != nil {
+			return nil, err
+		}
+		length += 4
+		if len(dst)+length > size {
+			return nil, &LZ4Error{Reason: "data larger than its declared size"}
+		}
+
+		// the match may overlap the bytes it produces, so it is copied byte by byte
+		var start = len(dst) - offset
+		for index := 0; index < length; index++ {
+			dst = append(dst, dst[start+index])
+		}
+	}
+	if len(dst) != size {
+		return nil, &LZ4Error{Reason: fmt.Sprintf("decompressed %d bytes instead of %d", len(dst), size)}
+	}
+	return dst, nil
+}
+
+// lz4Length reads the extra bytes of a length that starts from its nibble.
+func lz4Length(src []byte, pos *int, length int) (int, error) {
+	if length != 15 {
+		return length, nil
+	}
+	for {
+		if *pos >= len(src) {
+			return 0, &LZ4Error{Reason: "truncated length"}
+		}
+		var extra = src[*pos]
+		*pos++
+		length += int(extra)
+		if extra != 255 {
+			return length, nil
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package firefox
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// mozLz4 wraps data in a block of literals only, which is valid LZ4.
+func mozLz4(data []byte) []byte {
+	var out = append([]byte{}, mozLz4Magic...)
+	out = binary.LittleEndian.AppendUint32(out, uint32(len(data)))
+	if len(data) < 15 {
+		out = append(out, byte(len(data))<<4)
+	} else {
+		out = append(out, 0xf0)
+		var rest = len(data) - 15
+		for ; rest >= 255; rest -= 255 {
+			out = append(out, 255)
+		}
+		out = append(out, byte(rest))
+	}
+	return append(out, data...)
+}
+
+func TestDecodeLZ4Block(t *testing.T) {
+	var cases = []struct {
+		name  string
+		block []byte
+		size  int
+		want  string
+	}{
+		{"literals", []byte{0x30, 'a', 'b', 'c'}, 3, "abc"},
+		{"overlapping match", []byte{0x35, 'a', 'b', 'c', 0x03, 0x00, 0x30, 'X', 'Y', 'Z'}, 15, "abcabcabcabcXYZ"},
+		{"long match", append([]byte{0x1f, 'z', 0x01, 0x00, 0x02, 0x10}, '!'), 23, "zzzzzzzzzzzzzzzzzzzzzz!"},
+	}
+	for _, tc := range cases {
+		var got, err = decodeLZ4Block(tc.block, tc.size)
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		if string(got) != tc.want {
+			t.Errorf("%s: got %q, want %q", tc.name, got, tc.want)
+		}
+	}
+
+	var invalid = map[string][]byte{
+		"offset before start": {0x14, 'a', 0x02, 0x00},
+		"truncated literals":  {0x50, 'a', 'b'},
+		"truncated offset":    {0x10, 'a', 0x01},
+	}
+	for name, block := range invalid {
+		if _, err := decodeLZ4Block(block, 16); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+
+	// a header claiming 4 GiB must not be allocated
+	var header = append(append([]byte{}, mozLz4Magic...), 0xff, 0xff, 0xff, 0xff, 0x10, 'a')
+	if _, err := decodeMozLz4(header); err == nil {
+		t.Error("expected an error for an oversized declared size")
+	}
+}
+
+func TestSessionCookies(t *testing.T) {
+	var profile = t.TempDir()
+	var backups = filepath.Join(profile, "sessionstore-backups")
+	if err := os.MkdirAll(backups, 0750); err != nil {
+		t.Fatal(err)
+	}
+
+	var store = []byte(`{"version":["sessionrestore",1],"cookies":[` +
+		`{"host":".example.com","value":"abc","path":"/","name":"sid","secure":true,"httponly":true,"sameSite":1},` +
+		`{"host":"www.example.org","value":"1","path":"/app","name":"pref"}]}`)
+	if !bytes.Equal(mustDecode(t, mozLz4(store)), store) {
+		t.Fatal("round trip of the session store failed")
+	}
+	if err := os.WriteFile(filepath.Join(backups, "recovery.jsonlz4"), mozLz4(store), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	var core = newFirefoxCore()
+	core.profileDir = profile
+	var cookies, err = core.sessionCookies()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cookies) != 2 {
+		t.Fatalf("expected 2 session cookies, found %d", len(cookies))
+	}
+	var sid = cookies[0].httpCookie()
+	if sid.Name != "sid" || sid.Value != "abc" || !sid.Secure || !sid.HttpOnly || !sid.Expires.IsZero() || sid.SameSite != http.SameSiteLaxMode {
+		t.Errorf("unexpected cookie %+v", sid)
+	}
+}
+
+func mustDecode(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var decoded, err = decodeMozLz4(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return decoded
+}
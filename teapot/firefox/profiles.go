@@ -0,0 +1,228 @@
+package firefox
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// Profile is a Firefox profile listed in profiles.ini.
+type Profile struct {
+	// Name is the name shown in the profile manager, e.g. `default-release`.
+	Name string
+
+	// Path is the absolute directory of the profile.
+	Path string
+
+	// Default is true for the profile Firefox starts with.
+	Default bool
+}
+
+// DataPaths returns the Firefox directories holding a profiles.ini that
+// exist for the current user, the most likely one first.
+func DataPaths() []string {
+	var home, err = os.UserHomeDir()
+	if err != nil {
+		return nil
+	}
+
+	var candidates []string
+	if runtime.GOOS == "darwin" {
+		candidates = append(candidates, filepath.Join(home, "Library", "Application Support", "Firefox"))
+	} else {
+		candidates = append(candidates, filepath.Join(home, ".mozilla", "firefox"))
+		if config, err := os.UserConfigDir(); err == nil {
+			candidates = append(candidates, filepath.Join(config, "mozilla", "firefox"))
+		}
+		candidates = append(
+			candidates,
+			filepath.Join(home, "snap", "firefox", "common", ".mozilla", "firefox"),
+			filepath.Join(home, ".var", "app", "org.mozilla.firefox", ".mozilla", "firefox"),
+		)
+	}
+
+	var found = make([]string, 0, len(candidates))
+	for _, candidate := range candidates {
+		if _, err = os.Stat(filepath.Join(candidate, "profiles.ini")); err == nil {
+			found = append(found, candidate)
+		}
+	}
+	return found
+}
+
+// Profiles lists the profiles of the data path set with UseDataPath, or
+// of the first one found by DataPaths otherwise.
+func Profiles(options ...Option) ([]*Profile, error) {
+	var core = newFirefoxCore(options...)
+	if err := core.loadDataPath(); err != nil {
+		return nil, err
+	}
+	return readProfiles(core.datapath)
+}
+
+// readProfiles parses profiles.ini of the data path; the default profile
+// of the installation in installs.ini wins over the one of profiles.ini,
+// which only reflects what older versions of Firefox last used.
+func readProfiles(datapath string) ([]*Profile, error) {
+	var sections, err = readINI(filepath.Join(datapath, "profiles.ini"))
+	if err != nil {
+		return nil, err
+	}
+
+	var installDefaults []string
+	if installs, err := readINI(filepath.Join(datapath, "installs.ini")); err == nil {
+		installDefaults = append(installDefaults, sectionValues(installs, "", "Default")...)
+	}
+	installDefaults = append(installDefaults, sectionValues(sections, "Install", "Default")...)
+
+	var profiles []*Profile
+	var legacyDefault *Profile
+	for _, name := range sortedSections(sections, "Profile") {
+		var section = sections[name]
+		if section["Path"] == "" {
+			continue
+		}
+		var profile = &Profile{Name: section["Name"], Path: filepath.FromSlash(section["Path"])}
+		if section["IsRelative"] != "0" {
+			profile.Path = filepath.Join(datapath, profile.Path)
+		}
+		if section["Default"] == "1" && legacyDefault == nil {
+			legacyDefault = profile
+		}
+		profiles = append(profiles, profile)
+	}
+	if len(profiles) == 0 {
+		return nil, &ProfileNotFoundError{DataPath: datapath}
+	}
+
+	for _, install := range installDefaults {
+		for _, profile := range profiles {
+			var path = filepath.FromSlash(install)
+			if filepath.Join(datapath, path) == profile.Path || path == profile.Path {
+				profile.Default = true
+				return profiles, nil
+			}
+		}
+	}
+	if legacyDefault != nil {
+		legacyDefault.Default = true
+	}
+	return profiles, nil
+}
+
+// findProfile returns the profile with the name, or the default one if the
+// name is empty; the name may also be the directory of the profile.
+func findProfile(profiles []*Profile, name string) *Profile {
+	for _, profile := range profiles {
+		if name == "" && profile.Default {
+			return profile
+		}
+		if name != "" && (profile.Name == name || filepath.Base(profile.Path) == name) {
+			return profile
+		}
+	}
+	if name == "" && len(profiles) != 0 {
+		return profiles[0]
+	}
+	return nil
+}
+
+// readINI parses the sections of an ini file as written by Firefox.
+func readINI(path string) (map[string]map[string]string, error) {
+	var file, err = os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var sections = make(map[string]map[string]string)
+	var current map[string]string
+	var scanner = bufio.NewScanner(file)
+	for scanner.Scan() {
+		var line = strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "" || strings.HasPrefix(line, ";") || strings.HasPrefix(line, "#"):
+		case strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]"):
+			current = make(map[string]string)
+			sections[line[1:len(line)-1]] = current
+		case current != nil && strings.Contains(line, "="):
+			var key, value, _ = strings.Cut(line, "=")
+			current[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+	return sections, scanner.Err()
+}
+
+// sortedSections returns the names of the sections with the prefix in
+// numeric order, so that Profile10 comes after Profile2.
+func sortedSections(sections map[string]map[string]string, prefix string) []string {
+	var names []string
+	for name := range sections {
+		if strings.HasPrefix(name, prefix) {
+			names = append(names, name)
+		}
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if len(names[i]) != len(names[j]) {
+			return len(names[i]) < len(names[j])
+		}
+		return names[i] < names[j]
+	})
+	return names
+}
+
+func sectionValues(sections map[string]map[string]string, prefix string, key string) []string {
+	var values []string
+	for _, name := range sortedSections(sections, prefix) {
+		if value := sections[name][key]; value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+func (core *firefoxCore) loadDataPath() error {
+	if core.datapath != "" {
+		return nil
+	}
+	var found = DataPaths()
+	if len(found) == 0 {
+		return new(DataPathNotFoundError)
+	}
+	core.datapath = found[0]
+	return nil
+}
+
+// loadProfile resolves the directory of the profile, reading profiles.ini
+// unless the profile is a directory below the data path already.
+func (core *firefoxCore) loadProfile() error {
+	if core.profile != "" {
+		for _, dir := range []string{
+			filepath.Join(core.datapath, "Profiles", core.profile),
+			filepath.Join(core.datapath, core.profile),
+		} {
+			if _, err := os.Stat(filepath.Join(dir, "cookies.sqlite")); err == nil {
+				core.profileDir = dir
+				return nil
+			}
+		}
+	}
+
+	var profiles, err = readProfiles(core.datapath)
+	if errors.Is(err, os.ErrNotExist) {
+		return &ProfileNotFoundError{DataPath: core.datapath, Name: core.profile}
+	} else if err != nil {
+		return err
+	}
+	var profile = findProfile(profiles, core.profile)
+	if profile == nil {
+		return &ProfileNotFoundError{DataPath: core.datapath, Name: core.profile}
+	}
+	core.log.Debugf("using Firefox profile %q at %s", profile.Name, profile.Path)
+	core.profileDir = profile.Path
+	return nil
+}
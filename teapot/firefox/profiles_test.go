@@ -0,0 +1,142 @@
+package firefox
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadProfiles(t *testing.T) {
+	var tests = []struct {
+		name     string
+		profiles string
+		installs string
+		order    []string // names of the profiles in the expected order
+		want     string   // name of the expected default profile
+	}{
+		{
+			name: "legacy default",
+			profiles: "[Profile0]\nName=one\nIsRelative=1\nPath=Profiles/one\n\n" +
+				"[Profile1]\nName=two\nIsRelative=1\nPath=Profiles/two\nDefault=1\n",
+			order: []string{"one", "two"},
+			want:  "two",
+		},
+		{
+			name: "install section wins over legacy default",
+			profiles: "[Install4F96D1932A9F858E]\nDefault=Profiles/one\nLocked=1\n\n" +
+				"[Profile0]\nName=one\nIsRelative=1\nPath=Profiles/one\n\n" +
+				"[Profile1]\nName=two\nIsRelative=1\nPath=Profiles/two\nDefault=1\n",
+			order: []string{"one", "two"},
+			want:  "one",
+		},
+		{
+			name: "installs.ini wins over install section",
+			profiles: "[Install4F96D1932A9F858E]\nDefault=Profiles/one\n\n" +
+				"[Profile0]\nName=one\nIsRelative=1\nPath=Profiles/one\n\n" +
+				"[Profile1]\nName=two\nIsRelative=1\nPath=Profiles/two\n\n" +
+				"[Profile2]\nName=three\nIsRelative=1\nPath=Profiles/three\nDefault=1\n",
+			installs: "[4F96D1932A9F858E]\nDefault=Profiles/two\nLocked=1\n",
+			order:    []string{"one", "two", "three"},
+			want:     "two",
+		},
+		{
+			name: "unknown install default falls back to legacy default",
+			profiles: "[Profile0]\nName=one\nIsRelative=1\nPath=Profiles/one\n\n" +
+				"[Profile1]\nName=two\nIsRelative=1\nPath=Profiles/two\nDefault=1\n",
+			installs: "[4F96D1932A9F858E]\nDefault=Profiles/gone\n",
+			order:    []string{"one", "two"},
+			want:     "two",
+		},
+		{
+			name: "absolute path",
+			profiles: "[Profile0]\nName=one\nIsRelative=1\nPath=Profiles/one\n\n" +
+				"[Profile1]\nName=elsewhere\nIsRelative=0\nPath={abs}/elsewhere\n",
+			installs: "[4F96D1932A9F858E]\nDefault={abs}/elsewhere\n",
+			order:    []string{"one", "elsewhere"},
+			want:     "elsewhere",
+		},
+		{
+			name: "numeric section order",
+			profiles: "[Profile10]\nName=ten\nIsRelative=1\nPath=Profiles/ten\n\n" +
+				"[Profile2]\nName=two\nIsRelative=1\nPath=Profiles/two\n\n" +
+				"[General]\nStartWithLastProfile=1\n\n" +
+				"[Profile1]\nName=one\nIsRelative=1\nPath=Profiles/one\n\n" +
+				"[Profile3]\nName=broken\n",
+			order: []string{"one", "two", "ten"},
+			want:  "one",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var datapath = t.TempDir()
+			var abs = filepath.ToSlash(t.TempDir())
+			var write = func(name string, content string) {
+				content = strings.ReplaceAll(content, "{abs}", abs)
+				if err := os.WriteFile(filepath.Join(datapath, name), []byte(content), 0600); err != nil {
+					t.Fatal(err)
+				}
+			}
+			write("profiles.ini", test.profiles)
+			if test.installs != "" {
+				write("installs.ini", test.installs)
+			}
+
+			var profiles, err = readProfiles(datapath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var names = make([]string, 0, len(profiles))
+			for _, profile := range profiles {
+				names = append(names, profile.Name)
+			}
+			if strings.Join(names, ",") != strings.Join(test.order, ",") {
+				t.Errorf("did not get expected profiles %q != %q", names, test.order)
+			}
+
+			var found = findProfile(profiles, "")
+			if found == nil || found.Name != test.want {
+				t.Fatalf("did not get expected default profile %+v != '%s'", found, test.want)
+			}
+			var dir = filepath.Join(datapath, "Profiles", test.want)
+			if test.want == "elsewhere" {
+				dir = filepath.Join(filepath.FromSlash(abs), "elsewhere")
+			}
+			if found.Path != dir {
+				t.Errorf("did not get expected path '%s' != '%s'", found.Path, dir)
+			}
+		})
+	}
+}
+
+func TestFindProfile_ByNameOrDirectory(t *testing.T) {
+	var profiles = []*Profile{
+		{Name: "default", Path: filepath.Join("data", "Profiles", "abcd.default")},
+		{Name: "work", Path: filepath.Join("data", "Profiles", "efgh.work"), Default: true},
+	}
+	for name, want := range map[string]*Profile{
+		"":             profiles[1],
+		"default":      profiles[0],
+		"efgh.work":    profiles[1],
+		"missing":      nil,
+		"abcd.default": profiles[0],
+	} {
+		if found := findProfile(profiles, name); found != want {
+			t.Errorf("%q: did not get expected profile %+v != %+v", name, found, want)
+		}
+	}
+}
+
+func TestReadProfiles_NoProfiles(t *testing.T) {
+	var datapath = t.TempDir()
+	if err := os.WriteFile(filepath.Join(datapath, "profiles.ini"), []byte("[General]\nVersion=2\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	var _, err = readProfiles(datapath)
+	var notFound *ProfileNotFoundError
+	if !errors.As(err, &notFound) {
+		t.Errorf("did not get expected ProfileNotFoundError: %v", err)
+	}
+}
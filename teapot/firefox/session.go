@@ -0,0 +1,85 @@
+package firefox
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// sessionFiles are where Firefox keeps the session store of a profile: the
+// backup written while it runs and the file written on a clean shutdown.
+var sessionFiles = []string{
+	filepath.Join("sessionstore-backups", "recovery.jsonlz4"),
+	"sessionstore.jsonlz4",
+}
+
+// FirefoxSessionCookie is a cookie of the session store, which holds the
+// session cookies that never make it into cookies.sqlite.
+type FirefoxSessionCookie struct {
+	Host     string `json:"host"`
+	Name     string `json:"name"`
+	Value    string `json:"value"`
+	Path     string `json:"path"`
+	Secure   bool   `json:"secure"`
+	HttpOnly bool   `json:"httponly"`
+	SameSite int64  `json:"sameSite"`
+}
+
+type sessionStore struct {
+	Cookies []FirefoxSessionCookie `json:"cookies"`
+}
+
+// sessionCookies reads the cookies of the newest session store of the
+// profile; there are none if Firefox has never stored a session.
+func (core *firefoxCore) sessionCookies() ([]FirefoxSessionCookie, error) {
+	for _, name := range sessionFiles {
+		var path = filepath.Join(core.profileDir, name)
+		var data, err = os.ReadFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		if data, err = decodeMozLz4(data); err != nil {
+			return nil, err
+		}
+
+		var store sessionStore
+		if err = json.Unmarshal(data, &store); err != nil {
+			return nil, err
+		}
+		core.log.Debugf("%d session cookies found in %s", len(store.Cookies), path)
+		return store.Cookies, nil
+	}
+	return nil, nil
+}
+
+// httpCookie converts the session cookie; it has no expiry since it only
+// lives as long as the session.
+func (cookie *FirefoxSessionCookie) httpCookie() *http.Cookie {
+	return &http.Cookie{
+		Name:     cookie.Name,
+		Value:    cookie.Value,
+		Path:     cookie.Path,
+		Domain:   cookie.Host,
+		Secure:   cookie.Secure,
+		HttpOnly: cookie.HttpOnly,
+		SameSite: sameSite(cookie.SameSite),
+	}
+}
+
+// sameSite maps the Firefox values, where 0 is None, to http.SameSite.
+func sameSite(value int64) http.SameSite {
+	switch value {
+	case 0:
+		return http.SameSiteNoneMode
+	case 1:
+		return http.SameSiteLaxMode
+	case 2:
+		return http.SameSiteStrictMode
+	default:
+		return http.SameSiteDefaultMode
+	}
+}
@@ -1,6 +1,7 @@
 package firefox
 
 import (
+	"fmt"
 	"net/http"
 	"net/url"
 )
@@ -11,6 +12,24 @@ func (e *EmptySqlitePathError) Error() string {
 	return "Empty path to sqlite Firefox cookie database"
 }
 
+type DataPathNotFoundError struct{}
+
+func (e *DataPathNotFoundError) Error() string {
+	return "No Firefox data path with a profiles.ini found"
+}
+
+type ProfileNotFoundError struct {
+	DataPath string
+	Name     string
+}
+
+func (e *ProfileNotFoundError) Error() string {
+	if e.Name == "" {
+		return fmt.Sprintf("No Firefox profile found in %s", e.DataPath)
+	}
+	return fmt.Sprintf("Firefox profile %q not found in %s", e.Name, e.DataPath)
+}
+
 // FirefoxSQLiteCookie
 //
 // https://firefox-source-docs.mozilla.org/devtools-user/storage_inspector/cookies/index.html
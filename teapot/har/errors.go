@@ -0,0 +1,22 @@
+package har
+
+import (
+	"fmt"
+)
+
+type FormatError struct {
+	Reason string
+}
+
+func (e *FormatError) Error() string {
+	return "invalid HAR: " + e.Reason
+}
+
+type NoMatchError struct {
+	Method string
+	URL    string
+}
+
+func (e *NoMatchError) Error() string {
+	return fmt.Sprintf("no HAR entry matches request: %s %s", e.Method, e.URL)
+}
@@ -0,0 +1,362 @@
+package har
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"gadget/storage"
+)
+
+// maxRecordedBody is how much of a body is kept; the rest of larger bodies
+// passes through without being held in memory and the entry says so in
+// its comment.
+const maxRecordedBody = 1 << 20
+
+// recorder captures every exchange passing through the RoundTrippers it
+// wraps. An exchange is recorded once its response body has been read to
+// the end or closed, so streams show up when they are done.
+type recorder struct {
+	mu      sync.Mutex
+	creator *Creator
+	entries []*Entry
+}
+
+// NewRecorder creates an empty recording; its Wrap method can be given
+// to teapot.Constructor.WrapTransport to capture a Session's traffic.
+func NewRecorder() *recorder {
+	return &recorder{creator: &Creator{Name: "gadget/teapot", Version: Version}}
+}
+
+// Wrap returns a RoundTripper that records into this recorder.
+func (rec *recorder) Wrap(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &recordingTransport{next: next, rec: rec}
+}
+
+// HAR returns a snapshot of everything recorded so far.
+func (rec *recorder) HAR() *HAR {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	return &HAR{Log: &Log{
+		Version: Version,
+		Creator: rec.creator,
+		Entries: append(make([]*Entry, 0, len(rec.entries)), rec.entries...),
+	}}
+}
+
+// Reset discards everything recorded so far.
+func (rec *recorder) Reset() {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	rec.entries = nil
+}
+
+// Save writes the recording to path atomically with restrictive permissions
+// since captures routinely contain credentials and cookies.
+func (rec *recorder) Save(path string) error {
+	var err error
+	var tmp *os.File
+
+	var dir = filepath.Dir(path)
+	if err = os.MkdirAll(dir, storage.MinDirPermission); err != nil {
+		return err
+	}
+	if tmp, err = os.CreateTemp(dir, ".har-*"); err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err = rec.HAR().Encode(tmp); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	if err = os.Chmod(tmp.Name(), storage.MinFilePermission); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (rec *recorder) add(entry *Entry) {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	rec.entries = append(rec.entries, entry)
+}
+
+type recordingTransport struct {
+	next http.RoundTripper
+	rec  *recorder
+}
+
+func (transport *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var err error
+	var resp *http.Response
+	var reqBody []byte
+	var digest = func() string { return "" }
+
+	if req.Body != nil && req.Body != http.NoBody {
+		if req.GetBody != nil {
+			var body io.ReadCloser
+			if body, err = req.GetBody(); err != nil {
+				return nil, err
+			}
+			var hash = sha256.New()
+			if reqBody, err = io.ReadAll(io.LimitReader(io.TeeReader(body, hash), maxRecordedBody+1)); err == nil {
+				_, err = io.Copy(hash, body)
+			}
+			_ = body.Close()
+			var sum = hex.EncodeToString(hash.Sum(nil))
+			digest = func() string { return sum }
+		} else {
+			// only the recorded part is read ahead, the rest follows as it is sent
+			var body = req.Body
+			reqBody, err = io.ReadAll(io.LimitReader(body, maxRecordedBody+1))
+			var sent = &digestingBody{
+				ReadCloser: body,
+				reader:     io.MultiReader(bytes.NewReader(reqBody), body),
+				hash:       sha256.New(),
+			}
+			req.Body = sent
+			digest = sent.digest
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var started = time.Now()
+	if resp, err = transport.next.RoundTrip(req); err != nil {
+		return nil, err
+	}
+	var waited = time.Since(started)
+
+	var request = newRequest(req, reqBody)
+	resp.Body = &recordingBody{ReadCloser: resp.Body, done: func(body []byte, size int64, complete bool) {
+		var received = time.Since(started) - waited
+		if request.PostData != nil {
+			request.BodyHash = digest()
+		}
+		var response = newResponse(resp, body)
+		response.BodySize = size
+		response.Content.Size = size
+		if !complete {
+			response.Content.Comment = "body was not read completely"
+			response.Content.Truncated = true
+		} else if size > int64(len(body)) {
+			response.Content.Comment = fmt.Sprintf("body truncated to %d of %d bytes", len(body), size)
+			response.Content.Truncated = true
+		}
+
+		transport.rec.add(&Entry{
+			StartedDateTime: started,
+			Time:            milliseconds(waited + received),
+			Request:         request,
+			Response:        response,
+			Cache:           &Cache{},
+			Timings: &Timings{
+				Blocked: -1,
+				DNS:     -1,
+				Connect: -1,
+				SSL:     -1,
+				Wait:    milliseconds(waited),
+				Receive: milliseconds(received),
+			},
+		})
+	}}
+
+	return resp, nil
+}
+
+// recordingBody keeps the first maxRecordedBody bytes of a response body
+// as it is read and hands them to done at the end of the body or when it
+// is closed, whichever comes first.
+type recordingBody struct {
+	io.ReadCloser
+	buffer bytes.Buffer
+	size   int64
+	done   func(body []byte, size int64, complete bool)
+}
+
+func (body *recordingBody) Read(p []byte) (int, error) {
+	var n, err = body.ReadCloser.Read(p)
+	if room := maxRecordedBody - body.buffer.Len(); room > 0 {
+		if room > n {
+			room = n
+		}
+		body.buffer.Write(p[:room])
+	}
+	body.size += int64(n)
+	if err == io.EOF {
+		body.finish(true)
+	}
+	return n, err
+}
+
+func (body *recordingBody) Close() error {
+	body.finish(false)
+	return body.ReadCloser.Close()
+}
+
+func (body *recordingBody) finish(complete bool) {
+	if body.done != nil {
+		body.done(body.buffer.Bytes(), body.size, complete)
+		body.done = nil
+	}
+}
+
+// digestingBody hashes a request body as it is sent; the digest is only
+// known once the body has been read to the end.
+type digestingBody struct {
+	io.ReadCloser
+	reader io.Reader
+	mu     sync.Mutex
+	hash   hash.Hash
+	sum    string
+}
+
+func (body *digestingBody) Read(p []byte) (int, error) {
+	var n, err = body.reader.Read(p)
+
+	body.mu.Lock()
+	defer body.mu.Unlock()
+	body.hash.Write(p[:n])
+	if err == io.EOF && body.sum == "" {
+		body.sum = hex.EncodeToString(body.hash.Sum(nil))
+	}
+	return n, err
+}
+
+func (body *digestingBody) digest() string {
+	body.mu.Lock()
+	defer body.mu.Unlock()
+	return body.sum
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+func nameValues(header http.Header) []*NameValue {
+	var pairs = make([]*NameValue, 0, len(header))
+	for name, values := range header {
+		for _, value := range values {
+			pairs = append(pairs, &NameValue{Name: name, Value: value})
+		}
+	}
+	return pairs
+}
+
+func newRequest(req *http.Request, body []byte) *Request {
+	var entry = &Request{
+		Method:      req.Method,
+		URL:         req.URL.String(),
+		HTTPVersion: protocol(req.Proto),
+		Cookies:     make([]*Cookie, 0),
+		Headers:     nameValues(req.Header),
+		QueryString: make([]*NameValue, 0),
+		HeadersSize: -1,
+		BodySize:    int64(len(body)),
+	}
+	if len(body) > maxRecordedBody {
+		body = body[:maxRecordedBody]
+		entry.BodySize = -1
+		if req.ContentLength > 0 {
+			entry.BodySize = req.ContentLength
+		}
+	}
+	for _, cookie := range req.Cookies() {
+		entry.Cookies = append(entry.Cookies, &Cookie{Name: cookie.Name, Value: cookie.Value})
+	}
+	for name, values := range req.URL.Query() {
+		for _, value := range values {
+			entry.QueryString = append(entry.QueryString, &NameValue{Name: name, Value: value})
+		}
+	}
+	if body != nil {
+		entry.PostData = &PostData{MimeType: req.Header.Get("Content-Type"), Text: string(body)}
+		if entry.BodySize != int64(len(body)) {
+			entry.PostData.Comment = fmt.Sprintf("body truncated to %d bytes", len(body))
+		}
+	}
+	return entry
+}
+
+func newResponse(resp *http.Response, body []byte) *Response {
+	var entry = &Response{
+		Status:      resp.StatusCode,
+		StatusText:  strings.TrimSpace(strings.TrimPrefix(resp.Status, strconv.Itoa(resp.StatusCode))),
+		HTTPVersion: protocol(resp.Proto),
+		Cookies:     make([]*Cookie, 0),
+		Headers:     nameValues(resp.Header),
+		Content:     newContent(resp.Header.Get("Content-Type"), body),
+		RedirectURL: resp.Header.Get("Location"),
+		HeadersSize: -1,
+		BodySize:    int64(len(body)),
+	}
+	for _, cookie := range resp.Cookies() {
+		var recorded = &Cookie{
+			Name:     cookie.Name,
+			Value:    cookie.Value,
+			Path:     cookie.Path,
+			Domain:   cookie.Domain,
+			HTTPOnly: cookie.HttpOnly,
+			Secure:   cookie.Secure,
+		}
+		if !cookie.Expires.IsZero() {
+			var expires = cookie.Expires
+			recorded.Expires = &expires
+		}
+		entry.Cookies = append(entry.Cookies, recorded)
+	}
+	return entry
+}
+
+// newContent stores textual bodies as is and anything else base64 encoded.
+func newContent(contentType string, body []byte) *Content {
+	var content = &Content{Size: int64(len(body)), MimeType: contentType}
+	if textual(contentType) && utf8.Valid(body) {
+		content.Text = string(body)
+	} else {
+		content.Text = base64.StdEncoding.EncodeToString(body)
+		content.Encoding = "base64"
+	}
+	return content
+}
+
+func textual(contentType string) bool {
+	var mediatype, _, err = mime.ParseMediaType(contentType)
+	if err != nil {
+		return contentType == ""
+	}
+	return strings.HasPrefix(mediatype, "text/") ||
+		strings.HasSuffix(mediatype, "json") ||
+		strings.HasSuffix(mediatype, "xml") ||
+		strings.HasSuffix(mediatype, "javascript") ||
+		mediatype == "application/x-www-form-urlencoded"
+}
+
+func protocol(proto string) string {
+	if proto == "" {
+		return "HTTP/1.1"
+	}
+	return proto
+}
@@ -0,0 +1,269 @@
+package har
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ReplayOption configures how requests are matched against the archive.
+type ReplayOption func(rpl *replayer)
+
+// MatchMethod requires the HTTP method to match; enabled by default.
+func MatchMethod(enabled bool) ReplayOption {
+	return func(rpl *replayer) {
+		rpl.method = enabled
+	}
+}
+
+// MatchURL requires the full URL to match; enabled by default. When
+// disabled, only the scheme, host and path are compared.
+func MatchURL(enabled bool) ReplayOption {
+	return func(rpl *replayer) {
+		rpl.fullURL = enabled
+	}
+}
+
+// MatchBody requires the SHA-256 digest of the request body to match.
+func MatchBody(enabled bool) ReplayOption {
+	return func(rpl *replayer) {
+		rpl.body = enabled
+	}
+}
+
+// MatchHeaders requires the values of the named request headers to match.
+func MatchHeaders(names ...string) ReplayOption {
+	return func(rpl *replayer) {
+		for _, name := range names {
+			rpl.headers = append(rpl.headers, http.CanonicalHeaderKey(name))
+		}
+	}
+}
+
+// Fallthrough sends requests without a matching entry to the wrapped
+// RoundTripper instead of failing them with a NoMatchError.
+func Fallthrough(rpl *replayer) {
+	rpl.passthrough = true
+}
+
+// replayer is an http.RoundTripper serving responses from an archive.
+//
+// Entries are matched in the order they were recorded and every entry is
+// served once, so a flow that requests the same URL repeatedly receives the
+// recorded responses in sequence; after all matching entries have been
+// served the last one keeps being repeated.
+type replayer struct {
+	mu      sync.Mutex
+	entries []*Entry
+	used    []bool
+	next    http.RoundTripper
+
+	method      bool
+	fullURL     bool
+	body        bool
+	headers     []string
+	passthrough bool
+}
+
+// NewReplayer creates a RoundTripper for the archive; its Wrap method can
+// be given to teapot.Constructor.WrapTransport.
+func NewReplayer(archive *HAR, options ...ReplayOption) *replayer {
+	var rpl = &replayer{method: true, fullURL: true}
+	if archive != nil && archive.Log != nil {
+		rpl.entries = archive.Log.Entries
+	}
+	rpl.used = make([]bool, len(rpl.entries))
+	for _, option := range options {
+		option(rpl)
+	}
+	return rpl
+}
+
+// Wrap returns a copy of the replayer that falls through to next when enabled.
+func (rpl *replayer) Wrap(next http.RoundTripper) http.RoundTripper {
+	rpl.mu.Lock()
+	defer rpl.mu.Unlock()
+
+	var wrapped = &replayer{
+		entries:     rpl.entries,
+		used:        make([]bool, len(rpl.entries)),
+		next:        next,
+		method:      rpl.method,
+		fullURL:     rpl.fullURL,
+		body:        rpl.body,
+		headers:     rpl.headers,
+		passthrough: rpl.passthrough,
+	}
+	return wrapped
+}
+
+func (rpl *replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	var err error
+	var digest string
+
+	if rpl.body {
+		var body []byte
+		if req.Body != nil && req.Body != http.NoBody {
+			if body, err = io.ReadAll(req.Body); err != nil {
+				return nil, err
+			}
+			_ = req.Body.Close()
+			req.Body = io.NopCloser(bytes.NewReader(body))
+		}
+		digest = sha256sum(body)
+	}
+
+	var entry = rpl.match(req, digest)
+	if entry == nil {
+		if rpl.passthrough && rpl.next != nil {
+			return rpl.next.RoundTrip(req)
+		}
+		return nil, &NoMatchError{Method: req.Method, URL: req.URL.String()}
+	}
+
+	return entry.Response.httpResponse(req)
+}
+
+func (rpl *replayer) match(req *http.Request, digest string) *Entry {
+	rpl.mu.Lock()
+	defer rpl.mu.Unlock()
+
+	var last = -1
+	for index, entry := range rpl.entries {
+		if !rpl.matches(entry, req, digest) {
+			continue
+		}
+		if !rpl.used[index] {
+			rpl.used[index] = true
+			return entry
+		}
+		last = index
+	}
+	if last < 0 {
+		return nil
+	}
+	return rpl.entries[last]
+}
+
+func (rpl *replayer) matches(entry *Entry, req *http.Request, digest string) bool {
+	if entry.Request == nil || entry.Response == nil {
+		return false
+	}
+	if rpl.method && !strings.EqualFold(entry.Request.Method, req.Method) {
+		return false
+	}
+
+	var recorded, err = url.Parse(entry.Request.URL)
+	if err != nil {
+		return false
+	}
+	if rpl.fullURL {
+		if recorded.String() != req.URL.String() {
+			return false
+		}
+	} else if recorded.Scheme != req.URL.Scheme || recorded.Host != req.URL.Host || recorded.Path != req.URL.Path {
+		return false
+	}
+
+	if rpl.body {
+		// archives from elsewhere only have the text of the body
+		var recordedDigest = entry.Request.BodyHash
+		if recordedDigest == "" {
+			var text string
+			if entry.Request.PostData != nil {
+				text = entry.Request.PostData.Text
+			}
+			recordedDigest = sha256sum([]byte(text))
+		}
+		if recordedDigest != digest {
+			return false
+		}
+	}
+
+	for _, name := range rpl.headers {
+		var values []string
+		for _, pair := range entry.Request.Headers {
+			if http.CanonicalHeaderKey(pair.Name) == name {
+				values = append(values, pair.Value)
+			}
+		}
+		if strings.Join(values, ",") != strings.Join(req.Header.Values(name), ",") {
+			return false
+		}
+	}
+
+	return true
+}
+
+func sha256sum(content []byte) string {
+	var sum = sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// httpResponse rebuilds the recorded response for req.
+func (rsp *Response) httpResponse(req *http.Request) (*http.Response, error) {
+	var err error
+	var body []byte
+
+	if rsp.Content != nil {
+		if rsp.Content.Encoding == "base64" {
+			if body, err = base64.StdEncoding.DecodeString(rsp.Content.Text); err != nil {
+				return nil, err
+			}
+		} else {
+			body = []byte(rsp.Content.Text)
+		}
+	}
+
+	var header = make(http.Header)
+	for _, pair := range rsp.Headers {
+		header.Add(pair.Name, pair.Value)
+	}
+	// the body has already been decoded when it was recorded
+	header.Del("Content-Encoding")
+	header.Set("Content-Length", strconv.Itoa(len(body)))
+
+	// the rest of a truncated body was never recorded, so reading it fails
+	// instead of passing the part off as the whole
+	var reader io.Reader = bytes.NewReader(body)
+	var length = int64(len(body))
+	if rsp.Content != nil && (rsp.Content.Truncated || rsp.Content.Size > length) {
+		reader = io.MultiReader(reader, failingReader{io.ErrUnexpectedEOF})
+		length = -1
+		header.Del("Content-Length")
+	}
+
+	var proto = protocol(rsp.HTTPVersion)
+	var major, minor, ok = http.ParseHTTPVersion(proto)
+	if !ok {
+		major, minor = 1, 1
+	}
+
+	return &http.Response{
+		Status:        strings.TrimSpace(strconv.Itoa(rsp.Status) + " " + rsp.StatusText),
+		StatusCode:    rsp.Status,
+		Proto:         proto,
+		ProtoMajor:    major,
+		ProtoMinor:    minor,
+		Header:        header,
+		Body:          io.NopCloser(reader),
+		ContentLength: length,
+		Request:       req,
+	}, nil
+}
+
+// failingReader fails every read with err.
+type failingReader struct {
+	err error
+}
+
+func (reader failingReader) Read([]byte) (int, error) {
+	return 0, reader.err
+}
@@ -0,0 +1,133 @@
+package har
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// roundTrip records the requests sent by send to a server running handler
+// and returns the archive after encoding and decoding it again, along with
+// the location of the server, which is gone by then.
+func roundTrip(t *testing.T, handler http.HandlerFunc, send func(client *http.Client, loc string)) (*HAR, string) {
+	var server = httptest.NewServer(handler)
+	defer server.Close()
+
+	var rec = NewRecorder()
+	send(&http.Client{Transport: rec.Wrap(nil)}, server.URL)
+
+	var encoded bytes.Buffer
+	if err := rec.HAR().Encode(&encoded); err != nil {
+		t.Fatal(err)
+	}
+	var archive, err = Decode(&encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return archive, server.URL
+}
+
+func readAll(t *testing.T, client *http.Client, req *http.Request) ([]byte, error) {
+	var resp, err = client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	return io.ReadAll(resp.Body)
+}
+
+func TestReplay_ServesRecordedResponses(t *testing.T) {
+	var binary = []byte{0x00, 0xff, 0xfe, 0x80, 'a'}
+	var archive, loc = roundTrip(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/binary" {
+			w.Header().Set("Content-Type", "application/octet-stream")
+			_, _ = w.Write(binary)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = w.Write([]byte("hello"))
+	}, func(client *http.Client, loc string) {
+		for _, path := range []string{"/text", "/binary"} {
+			var req, _ = http.NewRequest(http.MethodGet, loc+path, nil)
+			if _, err := readAll(t, client, req); err != nil {
+				t.Fatal(err)
+			}
+		}
+	})
+
+	var client = &http.Client{Transport: NewReplayer(archive)}
+	for path, expected := range map[string][]byte{"/text": []byte("hello"), "/binary": binary} {
+		var req, _ = http.NewRequest(http.MethodGet, loc+path, nil)
+		var body, err = readAll(t, client, req)
+		if err != nil || !bytes.Equal(body, expected) {
+			t.Errorf("%s: did not get expected body %q != %q (error=%v)", path, body, expected, err)
+		}
+	}
+}
+
+func TestReplay_FailsTruncatedResponses(t *testing.T) {
+	var size = maxRecordedBody + 1024
+	var archive, loc = roundTrip(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = w.Write(bytes.Repeat([]byte("a"), size))
+	}, func(client *http.Client, loc string) {
+		var req, _ = http.NewRequest(http.MethodGet, loc, nil)
+		var body, err = readAll(t, client, req)
+		if err != nil || len(body) != size {
+			t.Fatalf("did not get expected live body of %d bytes: %d (error=%v)", size, len(body), err)
+		}
+	})
+
+	var content = archive.Log.Entries[0].Response.Content
+	if !content.Truncated || content.Size != int64(size) || len(content.Text) != maxRecordedBody {
+		t.Errorf("did not get expected truncated content: size=%d text=%d", content.Size, len(content.Text))
+	}
+
+	var client = &http.Client{Transport: NewReplayer(archive)}
+	var req, _ = http.NewRequest(http.MethodGet, loc, nil)
+	var body, err = readAll(t, client, req)
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("did not get expected error: %v", err)
+	}
+	if len(body) != maxRecordedBody {
+		t.Errorf("did not get expected recorded part %d != %d", len(body), maxRecordedBody)
+	}
+}
+
+func TestReplay_MatchesLargeAndBinaryRequestBodies(t *testing.T) {
+	var large = bytes.Repeat([]byte("b"), 2*maxRecordedBody)
+	var binary = []byte{0x00, 0xff, 0xfe, 0x80}
+	var archive, loc = roundTrip(t, func(w http.ResponseWriter, r *http.Request) {
+		var body, _ = io.ReadAll(r.Body)
+		_, _ = w.Write([]byte{byte(len(body) % 251)})
+	}, func(client *http.Client, loc string) {
+		// without GetBody the body is hashed as it is sent
+		var req, _ = http.NewRequest(http.MethodPost, loc, io.NopCloser(bytes.NewReader(large)))
+		if _, err := readAll(t, client, req); err != nil {
+			t.Fatal(err)
+		}
+		req, _ = http.NewRequest(http.MethodPost, loc, bytes.NewReader(binary))
+		if _, err := readAll(t, client, req); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	var client = &http.Client{Transport: NewReplayer(archive, MatchBody(true))}
+	for _, content := range [][]byte{large, binary} {
+		var req, _ = http.NewRequest(http.MethodPost, loc, bytes.NewReader(content))
+		var body, err = readAll(t, client, req)
+		if err != nil || !bytes.Equal(body, []byte{byte(len(content) % 251)}) {
+			t.Errorf("did not get expected response for a body of %d bytes: %v (error=%v)", len(content), body, err)
+		}
+	}
+
+	var req, _ = http.NewRequest(http.MethodPost, loc, bytes.NewReader(large[1:]))
+	var _, err = client.Do(req)
+	var noMatch *NoMatchError
+	if !errors.As(err, &noMatch) {
+		t.Errorf("did not get expected NoMatchError: %v", err)
+	}
+}
@@ -0,0 +1,136 @@
+package teapot
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Hedge sends a second, identical request if the first has not completed
+// after delay and returns whichever succeeds first, cancelling the other.
+// Only GET and HEAD requests are hedged.
+func (session *teacup) Hedge(delay time.Duration) Requestor {
+	var clone = session.clone()
+	clone.hedge = delay
+	return clone
+}
+
+// Dedupe coalesces identical GET and HEAD requests that are in flight
+// on the Session at the same time into a single request.
+//
+// Every caller receives its own copy of the Result but they share the
+// Body, which must not be modified. The shared request keeps the values of
+// the context of the caller that started it but not its cancellation, so
+// it is only limited by the timeout of the Client; every caller stops
+// waiting when its own context ends.
+func (session *teacup) Dedupe() Requestor {
+	var clone = session.clone()
+	clone.dedupe = true
+	return clone
+}
+
+func idempotentRead(method string) bool {
+	switch strings.ToUpper(method) {
+	case "", http.MethodGet, http.MethodHead:
+		return true
+	}
+	return false
+}
+
+func (session *teacup) deduplicated(ctx context.Context, client *http.Client) *Result {
+	var flight = session.flights.DoChan(session.flightKey(), func() (interface{}, error) {
+		var shared, cancel = context.WithCancel(detachedContext{ctx})
+		if client.Timeout > 0 {
+			shared, cancel = context.WithTimeout(detachedContext{ctx}, client.Timeout)
+		}
+		defer cancel()
+		return session.hedged(shared, client), nil
+	})
+	select {
+	case <-ctx.Done():
+		return &Result{Error: ctx.Err()}
+	case outcome := <-flight:
+		var result = *outcome.Val.(*Result)
+		return &result
+	}
+}
+
+// detachedContext keeps the values of its parent but is never cancelled,
+// like context.WithoutCancel of Go 1.21.
+type detachedContext struct {
+	context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+func (detachedContext) Err() error {
+	return nil
+}
+
+// flightKey identifies requests that are identical for the purpose of
+// deduplication: the same method, location and headers.
+func (session *teacup) flightKey() string {
+	var key strings.Builder
+	key.WriteString(strings.ToUpper(session.method) + " " + session.location.String())
+
+	var names = make([]string, 0, len(session.headers))
+	for name := range session.headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		key.WriteString("\n" + name + ": " + strings.Join(session.headers[name], ", "))
+	}
+	return key.String()
+}
+
+func (session *teacup) hedged(ctx context.Context, client *http.Client) *Result {
+	if session.hedge <= 0 || !idempotentRead(session.method) {
+		return session.exchange(ctx, client, true)
+	}
+
+	var cancel context.CancelFunc
+	ctx, cancel = context.WithCancel(ctx)
+	defer cancel()
+
+	// buffered so that the request that loses does not block
+	var results = make(chan *Result, 2)
+	var send = func() {
+		results <- session.exchange(ctx, client, true)
+	}
+	go send()
+
+	var timer = time.NewTimer(session.hedge)
+	defer timer.Stop()
+
+	var hedged bool
+	var failed *Result
+	for {
+		select {
+		case <-timer.C:
+			hedged = true
+			go send()
+		case result := <-results:
+			switch {
+			case !hedged || hedgeSucceeded(result):
+				return result
+			case failed != nil:
+				return failed
+			default:
+				failed = result
+			}
+		}
+	}
+}
+
+func hedgeSucceeded(result *Result) bool {
+	return result.Error == nil && result.StatusCode() < http.StatusInternalServerError
+}
@@ -0,0 +1,126 @@
+package teapot
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHedge_SendsSecondRequestWhenFirstIsSlow(t *testing.T) {
+	var calls int32
+	var server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(time.Second):
+			}
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	var result = Builder().New().Session().
+		URLstring(server.URL).
+		Hedge(20 * time.Millisecond).
+		Get(context.Background())
+
+	if result.Error != nil || result.Text() != "ok" {
+		t.Fatalf("did not get expected result: status=%d error=%v", result.StatusCode(), result.Error)
+	}
+	if count := atomic.LoadInt32(&calls); count != 2 {
+		t.Errorf("did not get expected number of requests %d != 2", count)
+	}
+}
+
+func TestDedupe_CoalescesConcurrentRequests(t *testing.T) {
+	var calls int32
+	var server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(100 * time.Millisecond)
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	var session = Builder().New().Session()
+	var group sync.WaitGroup
+	for index := 0; index < 5; index++ {
+		group.Add(1)
+		go func() {
+			defer group.Done()
+			var result = session.URLstring(server.URL).Dedupe().Get(context.Background())
+			if result.Error != nil || result.Text() != "ok" {
+				t.Errorf("did not get expected result: status=%d error=%v", result.StatusCode(), result.Error)
+			}
+		}()
+	}
+	group.Wait()
+
+	if count := atomic.LoadInt32(&calls); count != 1 {
+		t.Errorf("did not get expected number of requests %d != 1", count)
+	}
+}
+
+func TestDedupe_OutlivesCancelledCaller(t *testing.T) {
+	var server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	var session = Builder().New().Session()
+	var ctx, cancel = context.WithCancel(context.Background())
+	var first = make(chan *Result, 1)
+	go func() {
+		first <- session.URLstring(server.URL).Dedupe().Get(ctx)
+	}()
+	time.Sleep(20 * time.Millisecond)
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		cancel()
+	}()
+
+	var result = session.URLstring(server.URL).Dedupe().Get(context.Background())
+	if result.Error != nil || result.Text() != "ok" {
+		t.Fatalf("did not get expected result: status=%d error=%v", result.StatusCode(), result.Error)
+	}
+	if cancelled := <-first; cancelled.Error == nil {
+		t.Errorf("expected the cancelled caller to stop waiting")
+	}
+}
+
+func TestDedupe_SeparatesSessionsWithOtherCredentials(t *testing.T) {
+	var calls int32
+	var server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(100 * time.Millisecond)
+		_, _ = w.Write([]byte(r.Header.Get("Authorization")))
+	}))
+	defer server.Close()
+
+	var session = Builder().New().Session()
+	var sessions = map[string]Session{
+		"Bearer alice": session.Mutate().Auth(BearerAuth("alice")).Make(),
+		"Bearer bob":   session.Mutate().Auth(BearerAuth("bob")).Make(),
+	}
+	var group sync.WaitGroup
+	for expected, session := range sessions {
+		group.Add(1)
+		go func(expected string, session Session) {
+			defer group.Done()
+			var result = session.URLstring(server.URL).Dedupe().Get(context.Background())
+			if result.Error != nil || result.Text() != expected {
+				t.Errorf("did not get expected response '%s' != '%s' (error=%v)", result.Text(), expected, result.Error)
+			}
+		}(expected, session)
+	}
+	group.Wait()
+
+	if count := atomic.LoadInt32(&calls); count != 2 {
+		t.Errorf("did not get expected number of requests %d != 2", count)
+	}
+}
@@ -11,6 +11,11 @@ const DefaultUserAgent = "teapot"
 type RequestInterceptor func(request *http.Request) error
 type ResponseInterceptor func(request *http.Response) error
 
+// TransportWrapper decorates the RoundTripper used by the Client, e.g. to
+// record or replay traffic. Wrappers sit directly on top of the transport
+// below the rate limits and cache, and each one wraps those added before it.
+type TransportWrapper func(next http.RoundTripper) http.RoundTripper
+
 var scrapingagents = []string{
 	"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/109.0.0.0 Safari/537.36",
 	"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/109.0.0.0 Safari/537.36",
@@ -0,0 +1,195 @@
+package teapot
+
+import (
+	"net/http"
+	"time"
+
+	"gadget/logging"
+)
+
+// Names of the built-in middlewares.
+const (
+	MiddlewareLogging = "logging"
+	MiddlewareTiming  = "timing"
+	MiddlewareHeaders = "headers"
+	MiddlewareErrors  = "errors"
+)
+
+// RoundTripFunc adapts a func to the http.RoundTripper interface.
+type RoundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (fn RoundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return fn(req)
+}
+
+// Middleware wraps every attempt of a Session request in the style of a
+// RoundTripper. It runs once per attempt around the Client, so it sees the
+// first request and the final response; redirects followed by the Client
+// in between are not passed through it, TransportWrappers see those.
+//
+// Middlewares run in the order of the chain, the first one being the
+// outermost. A middleware may change the request, inspect or replace the
+// response or return one of its own without calling next at all. Unlike
+// TransportWrappers they belong to the Session and can be changed per
+// Session; the Name is used to find the middleware in the chain.
+type Middleware struct {
+	Name string
+	Wrap TransportWrapper
+}
+
+// chainMiddleware returns the chain with middlewares appended, replacing
+// in place any middleware that has the same name.
+func chainMiddleware(chain []Middleware, middlewares ...Middleware) []Middleware {
+	var result = append(make([]Middleware, 0, len(chain)+len(middlewares)), chain...)
+	for _, middleware := range middlewares {
+		if index := middlewareIndex(result, middleware.Name); index >= 0 {
+			result[index] = middleware
+			continue
+		}
+		result = append(result, middleware)
+	}
+	return result
+}
+
+// insertMiddleware places a middleware next to the one named anchor; it
+// is appended if the anchor is not part of the chain.
+func insertMiddleware(chain []Middleware, anchor string, after bool, middleware Middleware) []Middleware {
+	var result = removeMiddleware(chain, middleware.Name)
+	var index = middlewareIndex(result, anchor)
+	if index < 0 {
+		return append(result, middleware)
+	}
+	if after {
+		index++
+	}
+	result = append(result[:index], append([]Middleware{middleware}, result[index:]...)...)
+	return result
+}
+
+func removeMiddleware(chain []Middleware, names ...string) []Middleware {
+	var result = make([]Middleware, 0, len(chain))
+	for _, middleware := range chain {
+		var removed bool
+		for _, name := range names {
+			if middleware.Name != "" && middleware.Name == name {
+				removed = true
+				break
+			}
+		}
+		if !removed {
+			result = append(result, middleware)
+		}
+	}
+	return result
+}
+
+func middlewareIndex(chain []Middleware, name string) int {
+	if name == "" {
+		return -1
+	}
+	for index := range chain {
+		if chain[index].Name == name {
+			return index
+		}
+	}
+	return -1
+}
+
+// buildChain wraps the final RoundTripper with every middleware.
+func buildChain(chain []Middleware, final http.RoundTripper) http.RoundTripper {
+	var rt = final
+	for index := len(chain) - 1; index >= 0; index-- {
+		if chain[index].Wrap != nil {
+			rt = chain[index].Wrap(rt)
+		}
+	}
+	return rt
+}
+
+// LoggingMiddleware logs every request and its outcome at the debug level,
+// and failures as warnings.
+func LoggingMiddleware(log logging.Logger) Middleware {
+	return Middleware{
+		Name: MiddlewareLogging,
+		Wrap: func(next http.RoundTripper) http.RoundTripper {
+			return RoundTripFunc(func(req *http.Request) (*http.Response, error) {
+				log.Debugw("sending request", "method", req.Method, "url", req.URL.Redacted())
+				var started = time.Now()
+				var resp, err = next.RoundTrip(req)
+				if err != nil {
+					log.Warnw(
+						"request failed",
+						"method", req.Method,
+						"url", req.URL.Redacted(),
+						"duration", time.Since(started),
+						"error", err,
+					)
+					return resp, err
+				}
+				log.Debugw(
+					"received response",
+					"method", req.Method,
+					"url", req.URL.Redacted(),
+					"status", resp.StatusCode,
+					"duration", time.Since(started),
+				)
+				return resp, err
+			})
+		},
+	}
+}
+
+// TimingMiddleware reports how long it took to receive the response headers
+// of every request; resp is nil if the request failed.
+func TimingMiddleware(observe func(req *http.Request, resp *http.Response, elapsed time.Duration)) Middleware {
+	return Middleware{
+		Name: MiddlewareTiming,
+		Wrap: func(next http.RoundTripper) http.RoundTripper {
+			return RoundTripFunc(func(req *http.Request) (*http.Response, error) {
+				var started = time.Now()
+				var resp, err = next.RoundTrip(req)
+				observe(req, resp, time.Since(started))
+				return resp, err
+			})
+		},
+	}
+}
+
+// HeaderMiddleware sets the headers on every request, replacing any
+// existing values unless overwrite is false.
+func HeaderMiddleware(header http.Header, overwrite bool) Middleware {
+	return Middleware{
+		Name: MiddlewareHeaders,
+		Wrap: func(next http.RoundTripper) http.RoundTripper {
+			return RoundTripFunc(func(req *http.Request) (*http.Response, error) {
+				req = req.Clone(req.Context())
+				CopyHeaders(req.Header, header, overwrite)
+				return next.RoundTrip(req)
+			})
+		},
+	}
+}
+
+// ErrorMiddleware lets mapper replace the response or error of every
+// request, e.g. to turn error statuses of an API into typed errors.
+// The mapper must close the body of a response it discards.
+func ErrorMiddleware(mapper func(resp *http.Response, err error) (*http.Response, error)) Middleware {
+	return Middleware{
+		Name: MiddlewareErrors,
+		Wrap: func(next http.RoundTripper) http.RoundTripper {
+			return RoundTripFunc(func(req *http.Request) (*http.Response, error) {
+				return mapper(next.RoundTrip(req))
+			})
+		},
+	}
+}
+
+// StatusErrors is an ErrorMiddleware mapper that fails every response
+// with a status of 400 or above with a StatusCodeError.
+func StatusErrors(resp *http.Response, err error) (*http.Response, error) {
+	if err != nil || resp.StatusCode < http.StatusBadRequest {
+		return resp, err
+	}
+	_ = resp.Body.Close()
+	return nil, &StatusCodeError{StatusCode: resp.StatusCode}
+}
@@ -15,21 +15,92 @@ func UseLogger(log logging.Logger) Option {
 	}
 }
 
+// UseConfig replaces the Config; the Client, rate limits, proxies and
+// circuits made from the previous one are dropped as well.
 func UseConfig(cfg *Config) Option {
 	return func(tpt *teapot) {
 		tpt.config = cfg
+		tpt.limiter = nil
+		tpt.proxies = nil
+		tpt.breaker = nil
+		tpt.resetClient()
+	}
+}
+
+func UseRetry(policy *RetryConfig) Option {
+	return func(tpt *teapot) {
+		tpt.retry = policy
+	}
+}
+
+// UseRateLimits replaces the per-host limits of the Config; Sessions and
+// clones made afterwards share a fresh set of limits.
+func UseRateLimits(limits ...*HostLimit) Option {
+	return func(tpt *teapot) {
+		tpt.limits = limits
+		tpt.limiter = nil
+		tpt.resetClient()
+	}
+}
+
+// UseCircuitBreaker stops requests to failing hosts according to cfg
+// instead of the Config.
+func UseCircuitBreaker(cfg *CircuitBreakerConfig) Option {
+	return func(tpt *teapot) {
+		tpt.breakerConfig = cfg
+		tpt.breaker = nil
+		tpt.resetClient()
+	}
+}
+
+// UseAccessLog logs every request with the Logger according to cfg.
+func UseAccessLog(cfg *AccessLogConfig) Option {
+	return func(tpt *teapot) {
+		tpt.accessLog = cfg
+	}
+}
+
+// UseProxy routes requests according to cfg instead of the Config.
+func UseProxy(cfg *ProxyConfig) Option {
+	return func(tpt *teapot) {
+		tpt.proxyConfig = cfg
+		tpt.proxies = nil
+		tpt.resetClient()
+	}
+}
+
+// UseCache enables the HTTP cache for the Client, keeping responses in store.
+func UseCache(store CacheStore) Option {
+	return func(tpt *teapot) {
+		tpt.cache = store
+		tpt.resetClient()
+	}
+}
+
+func UseTransportWrappers(wrappers []TransportWrapper) Option {
+	return func(tpt *teapot) {
+		tpt.wrappers = append(tpt.wrappers, wrappers...)
+		tpt.resetClient()
+	}
+}
+
+func UseAuth(auth Authenticator) Option {
+	return func(tpt *teapot) {
+		tpt.auth = auth
 	}
 }
 
 func UseTransport(transport *http.Transport) Option {
 	return func(tpt *teapot) {
 		tpt.transport = transport
+		tpt.resetClient()
 	}
 }
 
 func UseTLS(tlsconfig *tls.Config) Option {
 	return func(tpt *teapot) {
 		tpt.tlsconfig = tlsconfig
+		tpt.resetClient()
 	}
 }
 
@@ -49,6 +120,36 @@ func UseHeaders(headers http.Header, raw bool) Option {
 func UseCookieJar(jar http.CookieJar) Option {
 	return func(tpt *teapot) {
 		tpt.cookiejar = jar
+		tpt.httpclient = nil
+	}
+}
+
+// UseMiddleware appends middlewares to the chain of every Session,
+// replacing those with the same name.
+func UseMiddleware(middlewares ...Middleware) Option {
+	return func(tpt *teapot) {
+		tpt.middlewares = chainMiddleware(tpt.middlewares, middlewares...)
+	}
+}
+
+// UseMiddlewareBefore inserts a middleware in front of the one named anchor.
+func UseMiddlewareBefore(anchor string, middleware Middleware) Option {
+	return func(tpt *teapot) {
+		tpt.middlewares = insertMiddleware(tpt.middlewares, anchor, false, middleware)
+	}
+}
+
+// UseMiddlewareAfter inserts a middleware behind the one named anchor.
+func UseMiddlewareAfter(anchor string, middleware Middleware) Option {
+	return func(tpt *teapot) {
+		tpt.middlewares = insertMiddleware(tpt.middlewares, anchor, true, middleware)
+	}
+}
+
+// RemoveMiddleware removes the middlewares with the given names from the chain.
+func RemoveMiddleware(names ...string) Option {
+	return func(tpt *teapot) {
+		tpt.middlewares = removeMiddleware(tpt.middlewares, names...)
 	}
 }
 
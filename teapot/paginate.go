@@ -0,0 +1,331 @@
+package teapot
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// PageStrategy determines the request for the page following a Result.
+//
+// Next returns nil when there are no more pages; req is the Requestor
+// that produced prev and can be mutated to build the next one.
+type PageStrategy interface {
+	Next(req Requestor, prev *Result) (Requestor, error)
+}
+
+// PageStrategyFunc adapts a func to the PageStrategy interface.
+type PageStrategyFunc func(req Requestor, prev *Result) (Requestor, error)
+
+func (fn PageStrategyFunc) Next(req Requestor, prev *Result) (Requestor, error) {
+	return fn(req, prev)
+}
+
+// Pages lazily requests one page after another, e.g.:
+//
+//	var pages = session.URLstring(loc).Paginate(teapot.LinkHeader("next"), 100)
+//	for pages.Next(ctx) {
+//		handle(pages.Page())
+//	}
+//	if err := pages.Err(); err != nil {
+//		...
+//	}
+type Pages struct {
+	next     Requestor
+	strategy PageStrategy
+	method   string
+	maxPages int
+	fetched  int
+	page     *Result
+	err      error
+}
+
+// Paginate starts at the first page described by req; a maxPages of
+// zero or less does not limit the number of pages.
+func Paginate(req Requestor, strategy PageStrategy, maxPages int) *Pages {
+	return &Pages{next: req, strategy: strategy, method: http.MethodGet, maxPages: maxPages}
+}
+
+func (session *teacup) Paginate(strategy PageStrategy, maxPages int) *Pages {
+	return Paginate(session, strategy, maxPages)
+}
+
+// Method changes the HTTP method used for every page from GET.
+func (pages *Pages) Method(method string) *Pages {
+	pages.method = method
+	return pages
+}
+
+// Next requests the following page and reports whether one was retrieved;
+// it stops when the strategy runs out of pages, the page cap is reached, a
+// request fails or the context ends. A page without a 2xx status fails with
+// a StatusCodeError and is left in Page for inspection.
+func (pages *Pages) Next(ctx context.Context) bool {
+	if pages.err != nil || pages.next == nil {
+		return false
+	}
+	if pages.maxPages > 0 && pages.fetched >= pages.maxPages {
+		return false
+	}
+	if pages.err = ctx.Err(); pages.err != nil {
+		return false
+	}
+
+	var current = pages.next
+	pages.page = current.Request(ctx, pages.method)
+	if pages.err = pages.page.ExpectSuccess().Error; pages.err != nil {
+		return false
+	}
+	pages.fetched++
+
+	if pages.next, pages.err = pages.strategy.Next(current, pages.page); pages.err != nil {
+		return false
+	}
+	return true
+}
+
+// Page returns the Result of the page retrieved by the last call to Next.
+func (pages *Pages) Page() *Result {
+	return pages.page
+}
+
+// Fetched returns the number of pages retrieved so far.
+func (pages *Pages) Fetched() int {
+	return pages.fetched
+}
+
+// Err returns the error that stopped the iteration, if any.
+func (pages *Pages) Err() error {
+	return pages.err
+}
+
+// Items lazily yields the items decoded from every page.
+type Items[T any] struct {
+	pages   *Pages
+	extract func(res *Result) ([]T, error)
+	buffer  []T
+	item    T
+	err     error
+}
+
+// PageItems iterates over the items extracted from each of the pages.
+func PageItems[T any](pages *Pages, extract func(res *Result) ([]T, error)) *Items[T] {
+	return &Items[T]{pages: pages, extract: extract}
+}
+
+// Next advances to the following item, requesting pages as needed.
+func (items *Items[T]) Next(ctx context.Context) bool {
+	for len(items.buffer) == 0 {
+		if items.err != nil || !items.pages.Next(ctx) {
+			return false
+		}
+		if items.buffer, items.err = items.extract(items.pages.Page()); items.err != nil {
+			return false
+		}
+	}
+	items.item, items.buffer = items.buffer[0], items.buffer[1:]
+	return true
+}
+
+// Item returns the item reached by the last call to Next.
+func (items *Items[T]) Item() T {
+	return items.item
+}
+
+// Err returns the error that stopped the iteration, if any.
+func (items *Items[T]) Err() error {
+	if items.err != nil {
+		return items.err
+	}
+	return items.pages.Err()
+}
+
+// JSONItems extracts the array found at a dot separated field path of a
+// JSON body, e.g. `data.items`; an empty path expects the body to be an array.
+func JSONItems[T any](path string) func(res *Result) ([]T, error) {
+	return func(res *Result) ([]T, error) {
+		var items []T
+		var raw, err = jsonField(res.Body, path)
+		if err != nil || raw == nil {
+			return nil, err
+		}
+		if err = json.Unmarshal(raw, &items); err != nil {
+			return nil, err
+		}
+		return items, nil
+	}
+}
+
+// jsonField descends into nested objects along a dot separated path,
+// returning nil if the field does not exist or is null.
+func jsonField(body []byte, path string) (json.RawMessage, error) {
+	var raw = json.RawMessage(body)
+	if path == "" {
+		return raw, nil
+	}
+	for _, name := range strings.Split(path, ".") {
+		var object map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &object); err != nil {
+			return nil, err
+		}
+		var found bool
+		if raw, found = object[name]; !found || string(raw) == "null" {
+			return nil, nil
+		}
+	}
+	return raw, nil
+}
+
+// LinkHeader follows the URL of the given relation, usually `next`, in
+// the `Link` header of RFC 8288.
+func LinkHeader(rel string) PageStrategy {
+	return PageStrategyFunc(func(req Requestor, prev *Result) (Requestor, error) {
+		if prev.Response == nil {
+			return nil, nil
+		}
+		for _, link := range ParseLinkHeader(prev.Response.Header.Values("Link")) {
+			if !link.Has(rel) {
+				continue
+			}
+			var base = prev.Response.Request.URL
+			var target, err = base.Parse(link.URL)
+			if err != nil {
+				return nil, err
+			}
+			return req.URL(target), nil
+		}
+		return nil, nil
+	})
+}
+
+// Link is a single target of a `Link` header.
+type Link struct {
+	URL    string
+	Params map[string]string
+}
+
+// Has reports whether the link has a relation type; `rel` may list several.
+func (link Link) Has(rel string) bool {
+	for _, value := range strings.Fields(link.Params["rel"]) {
+		if strings.EqualFold(value, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseLinkHeader parses the values of `Link` headers per RFC 8288.
+func ParseLinkHeader(values []string) []Link {
+	var links []Link
+	for _, value := range values {
+		for value != "" {
+			var start = strings.IndexByte(value, '<')
+			var end = strings.IndexByte(value, '>')
+			if start < 0 || end < start {
+				break
+			}
+			var link = Link{URL: value[start+1 : end], Params: make(map[string]string)}
+			value = value[end+1:]
+
+			// parameters continue until the next link, which starts with a comma outside quotes
+			var params string
+			var quoted bool
+			var index = 0
+			for ; index < len(value); index++ {
+				if value[index] == '"' {
+					quoted = !quoted
+				} else if value[index] == ',' && !quoted {
+					break
+				}
+			}
+			params, value = value[:index], strings.TrimLeft(value[index:], ", ")
+			for _, param := range strings.Split(params, ";") {
+				var name, arg, _ = strings.Cut(strings.TrimSpace(param), "=")
+				if name != "" {
+					link.Params[strings.ToLower(name)] = strings.Trim(arg, `"`)
+				}
+			}
+			links = append(links, link)
+		}
+	}
+	return links
+}
+
+// Cursor requests the next page by setting a query parameter to the cursor
+// returned by extract; an empty cursor ends the pagination.
+func Cursor(param string, extract func(res *Result) (string, error)) PageStrategy {
+	return PageStrategyFunc(func(req Requestor, prev *Result) (Requestor, error) {
+		var cursor, err = extract(prev)
+		if err != nil || cursor == "" {
+			return nil, err
+		}
+		return req.URL(withQuery(prev.Request.URL, param, cursor)), nil
+	})
+}
+
+// JSONCursor extracts a cursor from a dot separated field path of a JSON body.
+func JSONCursor(param string, path string) PageStrategy {
+	return Cursor(param, func(res *Result) (string, error) {
+		var raw, err = jsonField(res.Body, path)
+		if err != nil || raw == nil {
+			return "", err
+		}
+		// numbers are kept as they are, large IDs do not fit a float64
+		var cursor any
+		var decoder = json.NewDecoder(bytes.NewReader(raw))
+		decoder.UseNumber()
+		if err = decoder.Decode(&cursor); err != nil {
+			return "", err
+		}
+		switch value := cursor.(type) {
+		case string:
+			return value, nil
+		case json.Number:
+			return value.String(), nil
+		default:
+			return "", nil
+		}
+	})
+}
+
+// OffsetLimit requests pages of limit items by incrementing the offset
+// parameter; count reports the number of items on a page and a page
+// with fewer than limit items is the last one.
+func OffsetLimit(offsetParam string, limitParam string, limit int, count func(res *Result) (int, error)) PageStrategy {
+	return PageStrategyFunc(func(req Requestor, prev *Result) (Requestor, error) {
+		var items, err = count(prev)
+		if err != nil || items < limit {
+			return nil, err
+		}
+		var offset int
+		if value := prev.Request.URL.Query().Get(offsetParam); value != "" {
+			if offset, err = strconv.Atoi(value); err != nil {
+				return nil, err
+			}
+		}
+		var next = withQuery(prev.Request.URL, offsetParam, strconv.Itoa(offset+items))
+		next = withQuery(next, limitParam, strconv.Itoa(limit))
+		return req.URL(next), nil
+	})
+}
+
+// JSONCount counts the items of the array at a dot separated field path
+// of a JSON body, for use with OffsetLimit.
+func JSONCount(path string) func(res *Result) (int, error) {
+	return func(res *Result) (int, error) {
+		var items, err = JSONItems[json.RawMessage](path)(res)
+		return len(items), err
+	}
+}
+
+func withQuery(loc *url.URL, param string, value string) *url.URL {
+	var next = *loc
+	var query = next.Query()
+	query.Set(param, value)
+	next.RawQuery = query.Encode()
+	return &next
+}
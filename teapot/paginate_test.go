@@ -0,0 +1,63 @@
+package teapot
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPages_FailsOnErrorStatus(t *testing.T) {
+	var server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("cursor") == "" {
+			_, _ = w.Write([]byte(`{"items": [1, 2], "next": "b"}`))
+			return
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = w.Write([]byte(`{"items": [], "next": null}`))
+	}))
+	defer server.Close()
+
+	var pages = Builder().New().Session().URLstring(server.URL).Paginate(JSONCursor("cursor", "next"), 0)
+	var items = PageItems(pages, JSONItems[int]("items"))
+	var count int
+	for items.Next(context.Background()) {
+		count++
+	}
+	if count != 2 {
+		t.Errorf("did not get expected number of items %d != 2", count)
+	}
+
+	var statusErr *StatusCodeError
+	if !errors.As(items.Err(), &statusErr) || statusErr.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("did not get expected error: %v", items.Err())
+	}
+	if pages.Page().StatusCode() != http.StatusServiceUnavailable {
+		t.Errorf("did not get expected failed page: %d", pages.Page().StatusCode())
+	}
+}
+
+func TestJSONCursor_KeepsLargeNumbers(t *testing.T) {
+	var cursors []string
+	var server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var cursor = r.URL.Query().Get("after")
+		cursors = append(cursors, cursor)
+		if cursor == "" {
+			_, _ = w.Write([]byte(`{"last_id": 1234567890123456789}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"last_id": null}`))
+	}))
+	defer server.Close()
+
+	var pages = Builder().New().Session().URLstring(server.URL).Paginate(JSONCursor("after", "last_id"), 0)
+	for pages.Next(context.Background()) {
+	}
+	if pages.Err() != nil {
+		t.Fatal(pages.Err())
+	}
+	if len(cursors) != 2 || cursors[1] != "1234567890123456789" {
+		t.Errorf("did not get expected cursors %q", cursors)
+	}
+}
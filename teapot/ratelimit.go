@@ -0,0 +1,205 @@
+package teapot
+
+import (
+	"io"
+	"math"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HostLimit paces the requests made to the hosts matching a pattern.
+//
+// Host is either an exact host name or a pattern in the syntax of
+// path.Match, e.g. `*.example.com`; the first matching HostLimit in
+// the Config is used for a request and `*` can be used as a catch-all.
+// Ports are not part of the match.
+type HostLimit struct {
+	// Host is the host name or pattern the limit applies to.
+	Host string `mapstructure:"host" json:"host"`
+
+	// Rate is the number of requests per second that are allowed
+	// to start, refilling a token bucket of size Burst. Zero means
+	// the rate is not limited.
+	Rate float64 `mapstructure:"rate" json:"rate,omitempty"`
+
+	// Burst is the number of requests that may start at once when
+	// the bucket is full. Values less than 1 are treated as 1.
+	Burst int `mapstructure:"burst" json:"burst,omitempty"`
+
+	// MaxInFlight caps the number of requests that may be waiting for or
+	// reading a response at the same time. Zero means no limit.
+	MaxInFlight int `mapstructure:"max_in_flight" json:"max_in_flight,omitempty"`
+
+	// Shared makes every host matching a pattern draw from the same limit
+	// instead of each host receiving its own.
+	Shared bool `mapstructure:"shared" json:"shared,omitempty"`
+}
+
+func (limit *HostLimit) matches(host string) bool {
+	if strings.EqualFold(limit.Host, host) {
+		return true
+	}
+	var matched, err = path.Match(strings.ToLower(limit.Host), host)
+	return err == nil && matched
+}
+
+// hostLimiter holds the live limits for a Teapot and every Session or
+// clone made from it so that they are all paced together.
+type hostLimiter struct {
+	mu      sync.Mutex
+	limits  []*HostLimit
+	buckets map[string]*bucket
+}
+
+func newHostLimiter(limits []*HostLimit) *hostLimiter {
+	return &hostLimiter{limits: limits, buckets: make(map[string]*bucket)}
+}
+
+// bucket finds or creates the bucket for a host; nil means it is unlimited.
+func (limiter *hostLimiter) bucket(host string) *bucket {
+	host = strings.ToLower(host)
+
+	for _, limit := range limiter.limits {
+		if limit == nil || !limit.matches(host) {
+			continue
+		}
+
+		var key = limit.Host + "|" + host
+		if limit.Shared {
+			key = limit.Host
+		}
+
+		limiter.mu.Lock()
+		defer limiter.mu.Unlock()
+		if found, ok := limiter.buckets[key]; ok {
+			return found
+		}
+		var created = newBucket(limit)
+		limiter.buckets[key] = created
+		return created
+	}
+
+	return nil
+}
+
+// wrap returns a RoundTripper that waits for the host limits before each request.
+func (limiter *hostLimiter) wrap(next http.RoundTripper) http.RoundTripper {
+	if limiter == nil || len(limiter.limits) == 0 {
+		return next
+	}
+	return &limitedTransport{next: next, limiter: limiter}
+}
+
+type limitedTransport struct {
+	next    http.RoundTripper
+	limiter *hostLimiter
+}
+
+func (transport *limitedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var err error
+	var resp *http.Response
+	var bkt = transport.limiter.bucket(req.URL.Hostname())
+
+	if bkt == nil {
+		return transport.next.RoundTrip(req)
+	}
+
+	var release func()
+	if release, err = bkt.acquire(req); err != nil {
+		return nil, err
+	}
+	if resp, err = transport.next.RoundTrip(req); err != nil {
+		release()
+		return nil, err
+	}
+
+	// the request is in flight until the caller is done reading the response
+	resp.Body = &releasingBody{ReadCloser: resp.Body, release: release}
+	return resp, nil
+}
+
+// bucket is a token bucket combined with a semaphore for in-flight requests.
+type bucket struct {
+	mu       sync.Mutex
+	rate     float64
+	burst    float64
+	tokens   float64
+	last     time.Time
+	inflight chan struct{}
+}
+
+func newBucket(limit *HostLimit) *bucket {
+	var bkt = &bucket{rate: limit.Rate, burst: float64(limit.Burst), last: time.Now()}
+	if bkt.burst < 1 {
+		bkt.burst = 1
+	}
+	bkt.tokens = bkt.burst
+	if limit.MaxInFlight > 0 {
+		bkt.inflight = make(chan struct{}, limit.MaxInFlight)
+	}
+	return bkt
+}
+
+// acquire blocks until the request may be sent or its context ends; the
+// returned func must be called once the request is no longer in flight.
+func (bkt *bucket) acquire(req *http.Request) (func(), error) {
+	var ctx = req.Context()
+
+	if bkt.inflight != nil {
+		select {
+		case bkt.inflight <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	var release = func() {
+		if bkt.inflight != nil {
+			<-bkt.inflight
+		}
+	}
+
+	for bkt.rate > 0 {
+		var wait = bkt.take()
+		if wait == 0 {
+			break
+		}
+		if err := sleepContext(ctx, wait); err != nil {
+			release()
+			return nil, err
+		}
+	}
+
+	return release, nil
+}
+
+// take consumes a token if one is available, otherwise it returns how
+// long to wait until one will be.
+func (bkt *bucket) take() time.Duration {
+	bkt.mu.Lock()
+	defer bkt.mu.Unlock()
+
+	var now = time.Now()
+	bkt.tokens = math.Min(bkt.burst, bkt.tokens+now.Sub(bkt.last).Seconds()*bkt.rate)
+	bkt.last = now
+
+	if bkt.tokens >= 1 {
+		bkt.tokens--
+		return 0
+	}
+	return time.Duration((1 - bkt.tokens) / bkt.rate * float64(time.Second))
+}
+
+type releasingBody struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+func (body *releasingBody) Close() error {
+	var err = body.ReadCloser.Close()
+	body.once.Do(body.release)
+	return err
+}
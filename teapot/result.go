@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
+	"time"
 )
 
 func CopyHeaders(dst http.Header, src http.Header, overwrite bool) {
@@ -31,6 +32,32 @@ type Result struct {
 	Response *http.Response
 	Body     []byte
 	Error    error
+
+	// Attempts records every try made for the request, in order;
+	// the final entry corresponds to the Response and Error above.
+	Attempts []Attempt
+}
+
+// Attempt describes a single try of a request made by the Session.
+type Attempt struct {
+	Number     int
+	StatusCode int
+	Error      error
+	Duration   time.Duration
+
+	// Delay is how long the Session waited before the next attempt.
+	Delay time.Duration
+
+	// Timing is only captured for requests made with Requestor.Trace.
+	Timing *Timing
+}
+
+// Tries returns the number of attempts made for the request.
+func (res *Result) Tries() int {
+	if res == nil {
+		return 0
+	}
+	return len(res.Attempts)
 }
 
 func (res *Result) StatusCode() int {
@@ -95,7 +122,7 @@ func (res *Result) Dump() string {
 	if res.Request != nil {
 		// dump.WriteString("\nREQUEST: " + res.Request.URL.String() + "\n" + res.Request.Method)
 
-		dump.WriteString("\n" + res.Response.Proto)
+		dump.WriteString("\n" + res.Request.Proto)
 		dump.WriteString("\n" + res.Request.Method + " " + res.Request.URL.String())
 		for header, values := range res.Request.Header {
 			dump.WriteString("\n\t" + header + ": " + fmt.Sprintf("%v", values))
@@ -106,7 +133,9 @@ func (res *Result) Dump() string {
 
 		dump.WriteString("\n" + res.Response.Proto)
 		dump.WriteString("\n" + res.Response.Status)
-		dump.WriteString(" " + res.Response.Request.URL.String())
+		if res.Response.Request != nil {
+			dump.WriteString(" " + res.Response.Request.URL.String())
+		}
 		for header, values := range res.Response.Header {
 			dump.WriteString("\n\t" + header + ": " + fmt.Sprintf("%v", values))
 		}
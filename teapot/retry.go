@@ -0,0 +1,271 @@
+package teapot
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"errors"
+	"io"
+	"math"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// DefaultRetryStatuses are the HTTP status codes retried when a
+// RetryConfig does not explicitly list any.
+var DefaultRetryStatuses = []int{
+	http.StatusRequestTimeout,
+	http.StatusTooManyRequests,
+	http.StatusInternalServerError,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// DefaultRetryMethods are the idempotent HTTP methods retried when
+// a RetryConfig does not explicitly list any.
+var DefaultRetryMethods = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodOptions,
+	http.MethodPut,
+	http.MethodDelete,
+	http.MethodTrace,
+}
+
+// RetryConfig describes when a request should be attempted again and
+// how long to wait in between attempts.
+//
+// Delays grow exponentially from BaseDelay by Multiplier for every
+// attempt and are capped by MaxDelay; Jitter then randomizes a fraction
+// of each delay so that many clients failing at once do not retry in
+// lockstep. A `Retry-After` header sent by the server takes precedence
+// over the computed delay unless IgnoreRetryAfter is set.
+type RetryConfig struct {
+	// MaxAttempts is the total number of attempts made for a request,
+	// including the first one. Values less than 2 disable retries.
+	MaxAttempts int `mapstructure:"max_attempts" json:"max_attempts,omitempty"`
+
+	// Statuses lists the HTTP status codes that should be retried.
+	// If empty, DefaultRetryStatuses is used.
+	Statuses []int `mapstructure:"statuses" json:"statuses,omitempty"`
+
+	// Methods lists the HTTP methods that may be retried.
+	// If empty, DefaultRetryMethods is used.
+	Methods []string `mapstructure:"methods" json:"methods,omitempty"`
+
+	// NetworkErrors enables retrying of transport errors such as refused
+	// or reset connections, unexpected EOFs and timeouts. Cancellation of
+	// the request context is never retried.
+	NetworkErrors bool `mapstructure:"network_errors" json:"network_errors,omitempty"`
+
+	// BaseDelay is the delay before the first retry.
+	BaseDelay time.Duration `mapstructure:"base_delay" json:"base_delay,omitempty"`
+
+	// MaxDelay caps the delay before any retry, including delays
+	// requested by `Retry-After`. Zero means no limit.
+	MaxDelay time.Duration `mapstructure:"max_delay" json:"max_delay,omitempty"`
+
+	// Multiplier is the factor the delay grows by with every attempt.
+	// Values less than 1 are treated as 2.
+	Multiplier float64 `mapstructure:"multiplier" json:"multiplier,omitempty"`
+
+	// Jitter is the fraction, between 0 and 1, of each delay that is
+	// randomized. Zero disables jitter.
+	Jitter float64 `mapstructure:"jitter" json:"jitter,omitempty"`
+
+	// IgnoreRetryAfter disables honoring the `Retry-After` response header.
+	IgnoreRetryAfter bool `mapstructure:"ignore_retry_after" json:"ignore_retry_after,omitempty"`
+}
+
+// DefaultRetryConfig returns a conservative policy suitable for most APIs.
+func DefaultRetryConfig() *RetryConfig {
+	return &RetryConfig{
+		MaxAttempts:   3,
+		NetworkErrors: true,
+		BaseDelay:     time.Millisecond * 500,
+		MaxDelay:      time.Second * 30,
+		Multiplier:    2,
+		Jitter:        0.2,
+	}
+}
+
+func (cfg *RetryConfig) enabled() bool {
+	return cfg != nil && cfg.MaxAttempts > 1
+}
+
+// allowsMethod reports whether requests using method may be retried at all.
+func (cfg *RetryConfig) allowsMethod(method string) bool {
+	var methods = cfg.Methods
+	if len(methods) == 0 {
+		methods = DefaultRetryMethods
+	}
+	for _, allowed := range methods {
+		if allowed == method {
+			return true
+		}
+	}
+	return false
+}
+
+// retryStatus reports whether a response with the given status should be retried.
+func (cfg *RetryConfig) retryStatus(status int) bool {
+	var statuses = cfg.Statuses
+	if len(statuses) == 0 {
+		statuses = DefaultRetryStatuses
+	}
+	for _, retryable := range statuses {
+		if retryable == status {
+			return true
+		}
+	}
+	return false
+}
+
+// retryError reports whether a transport error should be retried.
+func (cfg *RetryConfig) retryError(ctx context.Context, err error) bool {
+	if !cfg.NetworkErrors || err == nil || ctx.Err() != nil {
+		return false
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	// an open circuit is meant to stop requests to the host
+	var open *CircuitOpenError
+	if errors.As(err, &open) {
+		return false
+	}
+
+	// certificate problems will not go away by asking again
+	var unknownAuthority x509.UnknownAuthorityError
+	var hostname x509.HostnameError
+	var invalid x509.CertificateInvalidError
+	if errors.As(err, &unknownAuthority) || errors.As(err, &hostname) || errors.As(err, &invalid) {
+		return false
+	}
+
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+
+	var neterr net.Error
+	if errors.As(err, &neterr) {
+		return true
+	}
+
+	return false
+}
+
+// delay calculates how long to wait after the given (1-based) attempt failed.
+func (cfg *RetryConfig) delay(attempt int, resp *http.Response) time.Duration {
+	if !cfg.IgnoreRetryAfter && resp != nil {
+		if wait, ok := ParseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+			if cfg.MaxDelay > 0 && wait > cfg.MaxDelay {
+				wait = cfg.MaxDelay
+			}
+			return wait
+		}
+	}
+
+	var multiplier = cfg.Multiplier
+	if multiplier < 1 {
+		multiplier = 2
+	}
+	var wait = float64(cfg.BaseDelay) * math.Pow(multiplier, float64(attempt-1))
+	if cfg.MaxDelay > 0 && wait > float64(cfg.MaxDelay) {
+		wait = float64(cfg.MaxDelay)
+	}
+	if cfg.Jitter > 0 {
+		var jitter = math.Min(cfg.Jitter, 1)
+		// #nosec G404 -- jitter does not need a cryptographically secure source
+		wait -= wait * jitter * rand.Float64()
+	}
+
+	return time.Duration(wait)
+}
+
+// ParseRetryAfter interprets the value of a `Retry-After` header, which
+// is either a number of seconds or an HTTP date, relative to now.
+func ParseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	if when, err := http.ParseTime(value); err == nil {
+		var wait = when.Sub(now)
+		if wait < 0 {
+			wait = 0
+		}
+		return wait, true
+	}
+	return 0, false
+}
+
+// rewindable ensures the request body can be replayed for every attempt.
+//
+// http.NewRequest already provides GetBody for the in-memory readers of
+// the bytes and strings packages; any other reader has to be buffered.
+func rewindable(req *http.Request) error {
+	if req.Body == nil || req.Body == http.NoBody || req.GetBody != nil {
+		return nil
+	}
+
+	var err error
+	var content []byte
+	if content, err = io.ReadAll(req.Body); err != nil {
+		return err
+	}
+	if err = req.Body.Close(); err != nil {
+		return err
+	}
+
+	req.ContentLength = int64(len(content))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(content)), nil
+	}
+	req.Body, _ = req.GetBody()
+
+	return nil
+}
+
+// rewind produces a copy of the request with a fresh body for another attempt.
+func rewind(req *http.Request) (*http.Request, error) {
+	var err error
+	var next = req.Clone(req.Context())
+	if req.GetBody != nil && req.Body != nil && req.Body != http.NoBody {
+		if next.Body, err = req.GetBody(); err != nil {
+			return nil, err
+		}
+	}
+	return next, nil
+}
+
+// sleepContext waits for the given duration unless the context ends first.
+func sleepContext(ctx context.Context, wait time.Duration) error {
+	if wait <= 0 {
+		return ctx.Err()
+	}
+
+	var timer = time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
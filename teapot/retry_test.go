@@ -0,0 +1,146 @@
+package teapot
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestParseRetryAfter_Seconds(t *testing.T) {
+	wait, ok := ParseRetryAfter("120", time.Now())
+	if !ok || wait != time.Second*120 {
+		t.Errorf("did not get expected delay '%v' != '%v'", wait, time.Second*120)
+	}
+}
+
+func TestParseRetryAfter_Date(t *testing.T) {
+	var now = time.Date(2023, 5, 31, 12, 0, 0, 0, time.UTC)
+	wait, ok := ParseRetryAfter(now.Add(time.Second*30).Format(http.TimeFormat), now)
+	if !ok || wait != time.Second*30 {
+		t.Errorf("did not get expected delay '%v' != '%v'", wait, time.Second*30)
+	}
+}
+
+func TestRetryConfig_RetriesStatusAndRewindsBody(t *testing.T) {
+	var calls int32
+	var server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body, _ = io.ReadAll(r.Body)
+		if string(body) != "payload" {
+			t.Errorf("did not get expected body on attempt %d: '%s'", atomic.LoadInt32(&calls)+1, body)
+		}
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	var policy = &RetryConfig{MaxAttempts: 5, Methods: []string{http.MethodPost}, BaseDelay: time.Millisecond}
+	var result = Builder().Retry(policy).New().Session().
+		URLstring(server.URL).
+		Body(io.NopCloser(strings.NewReader("payload"))).
+		Post(context.Background())
+
+	if result.Error != nil {
+		t.Fatalf("unexpected error: %v", result.Error)
+	}
+	if result.Tries() != 3 || result.StatusCode() != http.StatusOK || result.Text() != "ok" {
+		t.Errorf("did not get expected result: tries=%d status=%d body=%s", result.Tries(), result.StatusCode(), result.Text())
+	}
+}
+
+func TestRetryConfig_SendsJarCookiesOncePerAttempt(t *testing.T) {
+	var calls int32
+	var server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var number = atomic.AddInt32(&calls, 1)
+		if cookie := r.Header.Get("Cookie"); cookie != "sid=abc" {
+			t.Errorf("did not get expected cookie on attempt %d: '%s'", number, cookie)
+		}
+		if number < 4 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	var jar, _ = cookiejar.New(nil)
+	var location, _ = url.Parse(server.URL)
+	jar.SetCookies(location, []*http.Cookie{{Name: "sid", Value: "abc"}})
+
+	var policy = &RetryConfig{MaxAttempts: 4, BaseDelay: time.Millisecond}
+	var result = Builder().Retry(policy).CookieJar(jar).New().Session().
+		URLstring(server.URL).
+		Get(context.Background())
+
+	if result.Error != nil || result.Tries() != 4 {
+		t.Fatalf("did not get expected result: tries=%d error=%v", result.Tries(), result.Error)
+	}
+}
+
+// trackedEncoder counts the bodies it opens and how many of them are closed.
+type trackedEncoder struct {
+	opened int32
+	closed int32
+}
+
+func (enc *trackedEncoder) ContentType() string {
+	return "text/plain"
+}
+
+func (enc *trackedEncoder) ContentLength() int64 {
+	return int64(len("payload"))
+}
+
+func (enc *trackedEncoder) Open() (io.ReadCloser, error) {
+	atomic.AddInt32(&enc.opened, 1)
+	return &trackedBody{Reader: strings.NewReader("payload"), enc: enc}, nil
+}
+
+type trackedBody struct {
+	io.Reader
+	enc    *trackedEncoder
+	closed int32
+}
+
+func (body *trackedBody) Close() error {
+	if atomic.CompareAndSwapInt32(&body.closed, 0, 1) {
+		atomic.AddInt32(&body.enc.closed, 1)
+	}
+	return nil
+}
+
+func TestRetryConfig_ClosesEveryBody(t *testing.T) {
+	var calls int32
+	var server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.ReadAll(r.Body)
+		if atomic.AddInt32(&calls, 1) < 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	var enc = new(trackedEncoder)
+	var policy = &RetryConfig{MaxAttempts: 3, BaseDelay: time.Millisecond, Methods: []string{http.MethodPost}}
+	var result = Builder().Retry(policy).New().Session().
+		URLstring(server.URL).
+		Encode(enc).
+		Post(context.Background())
+
+	if result.Error != nil || result.Tries() != 2 {
+		t.Fatalf("did not get expected result: tries=%d error=%v", result.Tries(), result.Error)
+	}
+	if opened, closed := atomic.LoadInt32(&enc.opened), atomic.LoadInt32(&enc.closed); opened != closed {
+		t.Errorf("did not get expected number of closed bodies %d != %d", closed, opened)
+	}
+}
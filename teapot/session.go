@@ -5,8 +5,11 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"time"
 
 	"gadget/logging"
+
+	"golang.org/x/sync/singleflight"
 )
 
 type RequestMutator interface {
@@ -14,6 +17,25 @@ type RequestMutator interface {
 	URL(loc *url.URL) Requestor
 	Headers(headers http.Header) Requestor
 	Body(body io.Reader) Requestor
+
+	// Encode, JSON, Form and Multipart set the body along with its
+	// content headers; unlike Body, they can be replayed for retries and
+	// redirects without being buffered.
+	Encode(enc BodyEncoder) Requestor
+	JSON(v any) Requestor
+	Form(values url.Values) Requestor
+	Multipart(parts ...Part) Requestor
+
+	// Trace captures a Timing breakdown for every attempt of the request.
+	Trace() Requestor
+
+	// Hedge and Dedupe reduce the latency and load of idempotent reads.
+	Hedge(delay time.Duration) Requestor
+	Dedupe() Requestor
+
+	// MaxBodySize fails requests whose response body exceeds limit bytes
+	// with a BodyTooLargeError; zero disables the limit.
+	MaxBodySize(limit int64) Requestor
 }
 
 type Requestor interface {
@@ -27,6 +49,15 @@ type Requestor interface {
 	Patch(ctx context.Context) *Result
 	Delete(ctx context.Context) *Result
 	Options(ctx context.Context) *Result
+
+	// Stream sends the request without buffering the response body.
+	Stream(ctx context.Context, method string) *Stream
+
+	// Paginate iterates over the pages found by strategy, starting with this request.
+	Paginate(strategy PageStrategy, maxPages int) *Pages
+
+	// Curl returns a curl command line for the request without sending it.
+	Curl() (string, error)
 }
 
 type Session interface {
@@ -36,6 +67,7 @@ type Session interface {
 	Mutate() SessionMutator
 	Client() *http.Client
 	Jar() http.CookieJar
+	Middlewares() []Middleware
 }
 
 type SessionOption func(tcup *teacup)
@@ -45,8 +77,17 @@ type SessionMutator interface {
 	AddHeaders(headers http.Header) SessionMutator
 	SetHeaders(headers http.Header) SessionMutator
 	CookieJar(jar http.CookieJar) SessionMutator
+	Retry(policy *RetryConfig) SessionMutator
+	Auth(auth Authenticator) SessionMutator
+	AccessLog(cfg *AccessLogConfig) SessionMutator
 	OnRequest(handlers ...RequestInterceptor) SessionMutator
 	OnResponse(handlers ...ResponseInterceptor) SessionMutator
+
+	// Use appends middlewares to the chain, replacing those with the same name.
+	Use(middlewares ...Middleware) SessionMutator
+	UseBefore(name string, middleware Middleware) SessionMutator
+	UseAfter(name string, middleware Middleware) SessionMutator
+	Without(names ...string) SessionMutator
 	Make() Session
 }
 
@@ -84,6 +125,21 @@ func (mttr *sessionMutator) CookieJar(jar http.CookieJar) SessionMutator {
 	return mttr
 }
 
+func (mttr *sessionMutator) Retry(policy *RetryConfig) SessionMutator {
+	mttr.opts = append(mttr.opts, func(tcup *teacup) { tcup.retry = policy })
+	return mttr
+}
+
+func (mttr *sessionMutator) Auth(auth Authenticator) SessionMutator {
+	mttr.opts = append(mttr.opts, func(tcup *teacup) { tcup.auth = auth })
+	return mttr
+}
+
+func (mttr *sessionMutator) AccessLog(cfg *AccessLogConfig) SessionMutator {
+	mttr.opts = append(mttr.opts, func(tcup *teacup) { tcup.accessLog = cfg })
+	return mttr
+}
+
 func (mttr *sessionMutator) OnRequest(handlers ...RequestInterceptor) SessionMutator {
 	// NOTE: OnRequest will ADD handlers to what already exists!
 	mttr.opts = append(mttr.opts, func(tcup *teacup) { tcup.onRequest = append(tcup.onRequest, handlers...) })
@@ -96,11 +152,39 @@ func (mttr *sessionMutator) OnResponse(handlers ...ResponseInterceptor) SessionM
 	return mttr
 }
 
+func (mttr *sessionMutator) Use(middlewares ...Middleware) SessionMutator {
+	mttr.opts = append(mttr.opts, func(tcup *teacup) { tcup.middlewares = chainMiddleware(tcup.middlewares, middlewares...) })
+	return mttr
+}
+
+func (mttr *sessionMutator) UseBefore(name string, middleware Middleware) SessionMutator {
+	mttr.opts = append(mttr.opts, func(tcup *teacup) {
+		tcup.middlewares = insertMiddleware(tcup.middlewares, name, false, middleware)
+	})
+	return mttr
+}
+
+func (mttr *sessionMutator) UseAfter(name string, middleware Middleware) SessionMutator {
+	mttr.opts = append(mttr.opts, func(tcup *teacup) {
+		tcup.middlewares = insertMiddleware(tcup.middlewares, name, true, middleware)
+	})
+	return mttr
+}
+
+func (mttr *sessionMutator) Without(names ...string) SessionMutator {
+	mttr.opts = append(mttr.opts, func(tcup *teacup) { tcup.middlewares = removeMiddleware(tcup.middlewares, names...) })
+	return mttr
+}
+
 // Make will clone the teapot then modify and return the clone.
+//
+// The clone deduplicates its requests on its own, since its credentials,
+// jar or interceptors may change what a response holds.
 func (mttr *sessionMutator) Make() Session {
 	var tcup *teacup
 	if mttr.tcup != nil {
 		tcup = mttr.tcup.clone()
+		tcup.flights = new(singleflight.Group)
 	} else {
 		tcup = newTeacup()
 	}
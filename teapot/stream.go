@@ -0,0 +1,136 @@
+package teapot
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// Stream provides the live response of a request that was sent without
+// buffering the body, making it suitable for large downloads.
+//
+// The body must be consumed through the Stream, which implements
+// io.ReadCloser. It is closed automatically once it has been read to the
+// end, when reading fails, or when the request context ends; Close can be
+// called any number of times and callers should always defer it. Consume
+// wraps all of that for the common case.
+//
+// Unlike Result, the Client timeout does not apply to a Stream since it
+// would also limit the time spent reading the body; the request context
+// should be used to bound the transfer instead.
+type Stream struct {
+	Request  *http.Request
+	Response *http.Response
+	Error    error
+	Attempts []Attempt
+
+	body   io.ReadCloser
+	limit  int64
+	read   int64
+	once   sync.Once
+	closed chan struct{}
+	err    error
+}
+
+func (session *teacup) Stream(ctx context.Context, method string) *Stream {
+	session.method = method
+
+	var client = session.Client()
+	if client.Timeout != 0 {
+		var untimed = *client
+		untimed.Timeout = 0
+		client = &untimed
+	}
+
+	var result = session.exchange(ctx, client, false)
+	var stream = &Stream{
+		Request:  result.Request,
+		Response: result.Response,
+		Error:    result.Error,
+		Attempts: result.Attempts,
+		limit:    session.maxBody,
+		closed:   make(chan struct{}),
+	}
+	if stream.Error != nil || stream.Response == nil {
+		close(stream.closed)
+		return stream
+	}
+
+	stream.body = stream.Response.Body
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = stream.Close()
+		case <-stream.closed:
+		}
+	}()
+
+	return stream
+}
+
+func (st *Stream) StatusCode() int {
+	if st == nil || st.Response == nil {
+		return 0
+	}
+	return st.Response.StatusCode
+}
+
+// Tries returns the number of attempts made for the request.
+func (st *Stream) Tries() int {
+	if st == nil {
+		return 0
+	}
+	return len(st.Attempts)
+}
+
+func (st *Stream) Read(p []byte) (int, error) {
+	if st.Error != nil {
+		return 0, st.Error
+	}
+	if st.body == nil {
+		return 0, io.EOF
+	}
+
+	if st.limit > 0 && int64(len(p)) > st.limit-st.read+1 {
+		p = p[:st.limit-st.read+1]
+	}
+	var n, err = st.body.Read(p)
+	st.read += int64(n)
+	if st.limit > 0 && st.read > st.limit {
+		n -= int(st.read - st.limit)
+		st.read = st.limit
+		err = &BodyTooLargeError{Limit: st.limit}
+		st.Error = err
+	}
+	if err != nil {
+		_ = st.Close()
+	}
+
+	return n, err
+}
+
+// Close releases the response body; it is safe to call more than once.
+func (st *Stream) Close() error {
+	if st == nil || st.body == nil {
+		return nil
+	}
+	st.once.Do(func() {
+		st.err = st.body.Close()
+		close(st.closed)
+	})
+	return st.err
+}
+
+// Consume passes the body to fn and always closes the Stream afterwards.
+func (st *Stream) Consume(fn func(body io.Reader) error) error {
+	defer st.Close()
+
+	if st.Error != nil {
+		return st.Error
+	}
+	if err := fn(st); err != nil {
+		return err
+	}
+	return st.Error
+}
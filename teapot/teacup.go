@@ -6,8 +6,10 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
+	"time"
 
 	"github.com/carlmjohnson/requests"
+	"golang.org/x/sync/singleflight"
 
 	"gadget/logging"
 )
@@ -21,7 +23,7 @@ func newBuilder() *requests.Builder {
 }
 
 func newTeacup() *teacup {
-	return &teacup{Builder: newBuilder()}
+	return &teacup{Builder: newBuilder(), flights: new(singleflight.Group)}
 }
 
 // func newTeacup(c *http.Client, j http.CookieJar, h http.Header) *teacup {
@@ -39,23 +41,31 @@ func newTeacup() *teacup {
 type teacup struct {
 	*requests.Builder
 
-	log        logging.Logger
-	client     *http.Client
-	jar        http.CookieJar
-	headers    http.Header
-	onRequest  []RequestInterceptor
-	onResponse []ResponseInterceptor
+	log         logging.Logger
+	client      *http.Client
+	jar         http.CookieJar
+	headers     http.Header
+	retry       *RetryConfig
+	accessLog   *AccessLogConfig
+	auth        Authenticator
+	onRequest   []RequestInterceptor
+	onResponse  []ResponseInterceptor
+	middlewares []Middleware
 
 	location *url.URL
 	method   string
 	body     io.Reader
+	encoder  BodyEncoder
+	maxBody  int64
+	trace    bool
+	hedge    time.Duration
+	dedupe   bool
+	flights  *singleflight.Group
 	header   http.Header
 	err      error
 
-	// retryStatus []int
 	// blacklistStatus []int
 	// whitelistStatus []int
-
 }
 
 func (session *teacup) clone() *teacup {
@@ -79,12 +89,22 @@ func (session *teacup) clone() *teacup {
 			}
 			return session.headers.Clone()
 		}(),
-		onRequest:  session.onRequest[:],
-		onResponse: session.onResponse[:],
+		retry:       session.retry,
+		accessLog:   session.accessLog,
+		auth:        session.auth,
+		onRequest:   session.onRequest[:],
+		onResponse:  session.onResponse[:],
+		middlewares: append(make([]Middleware, 0, len(session.middlewares)), session.middlewares...),
 
 		location: locptr,
 		method:   session.method,
 		body:     session.body,
+		encoder:  session.encoder,
+		maxBody:  session.maxBody,
+		trace:    session.trace,
+		hedge:    session.hedge,
+		dedupe:   session.dedupe,
+		flights:  session.flights,
 		header: func() http.Header {
 			if session.header == nil {
 				return make(http.Header)
@@ -113,6 +133,10 @@ func (session *teacup) Jar() http.CookieJar {
 	return session.jar
 }
 
+func (session *teacup) Middlewares() []Middleware {
+	return session.middlewares
+}
+
 // func (session *teacup) SetHeader(key string, value string) {
 // 	session.headers.Set(key, value)
 // }
@@ -138,6 +162,50 @@ func (session *teacup) Headers(headers http.Header) Requestor {
 func (session *teacup) Body(body io.Reader) Requestor {
 	var clone = session.clone()
 	clone.body = body
+	clone.encoder = nil
+	return clone
+}
+
+func (session *teacup) Encode(enc BodyEncoder) Requestor {
+	var clone = session.clone()
+	clone.body = nil
+	clone.encoder = enc
+	return clone
+}
+
+func (session *teacup) JSON(v any) Requestor {
+	var enc, err = JSONBody(v)
+	if err != nil {
+		var clone = session.clone()
+		clone.err = err
+		return clone
+	}
+	return session.Encode(enc)
+}
+
+func (session *teacup) Form(values url.Values) Requestor {
+	return session.Encode(FormBody(values))
+}
+
+func (session *teacup) Multipart(parts ...Part) Requestor {
+	var enc, err = MultipartBody(parts...)
+	if err != nil {
+		var clone = session.clone()
+		clone.err = err
+		return clone
+	}
+	return session.Encode(enc)
+}
+
+func (session *teacup) MaxBodySize(limit int64) Requestor {
+	var clone = session.clone()
+	clone.maxBody = limit
+	return clone
+}
+
+func (session *teacup) Trace() Requestor {
+	var clone = session.clone()
+	clone.trace = true
 	return clone
 }
 
@@ -182,6 +250,16 @@ func (session *teacup) Options(ctx context.Context) *Result {
 }
 
 func (session *teacup) fetch(ctx context.Context) *Result {
+	if session.dedupe && session.flights != nil && session.err == nil && idempotentRead(session.method) {
+		return session.deduplicated(ctx, session.Client())
+	}
+	return session.hedged(ctx, session.Client())
+}
+
+// exchange sends the request, retrying as configured, and produces the
+// Result of the final attempt. Unless buffered, the body of the final
+// response is left open for the caller to read and close.
+func (session *teacup) exchange(ctx context.Context, client *http.Client, buffered bool) *Result {
 	// TODO: add additional functional options for unmarshalling json,
 	// checking headers like content-length, etc. so add hooks for intercepting
 	// the request and responses and propagating errors, maybe outside of the
@@ -198,40 +276,110 @@ func (session *teacup) fetch(ctx context.Context) *Result {
 		return &result
 	}
 
-	var loc = session.location.String()
-	if req, err = http.NewRequestWithContext(ctx, strings.ToUpper(session.method), loc, session.body); err != nil {
+	req, err = session.prepare(ctx)
+	result.Request = req
+	if err != nil {
 		result.Error = err
 		return &result
 	}
-	result.Request = req
 
-	for _, handler := range session.onRequest {
-		if err = handler(req); err != nil {
+	var attempts = 1
+	if session.retry.enabled() && session.retry.allowsMethod(req.Method) {
+		attempts = session.retry.MaxAttempts
+	}
+	var challenger, challengeable = session.auth.(Challenger)
+	if attempts > 1 || challengeable {
+		if err = rewindable(req); err != nil {
 			result.Error = err
 			return &result
 		}
 	}
 
-	// TODO: should this be done here or before applying onRequest?
-	CopyHeaders(req.Header, session.headers, false)
+	// every attempt starts from a copy of the prepared request since the
+	// Client adds the cookies of its jar to the request it is given
+	var prepared = req
+	var challenged bool
+	if prepared.GetBody != nil && prepared.Body != nil && prepared.Body != http.NoBody {
+		// only the copies are sent, so nothing else closes the original
+		_ = prepared.Body.Close()
+	}
+	for number := 1; number <= attempts; number++ {
+		if req, err = rewind(prepared); err != nil {
+			result.Error = err
+			return &result
+		}
+		result.Request = req
+		if session.auth != nil {
+			if err = session.auth.Authenticate(req); err != nil {
+				result.Error = err
+				return &result
+			}
+		}
 
-	if resp, err = session.Client().Do(req); err != nil {
-		result.Error = err
-		return &result
+		var attempt = Attempt{Number: number}
+		if session.trace {
+			attempt.Timing = new(Timing)
+		}
+		var started = time.Now()
+		resp, body, err = session.attempt(client, req, buffered, attempt.Timing)
+		attempt.Duration = time.Since(started)
+		attempt.Error = err
+		if resp != nil {
+			attempt.StatusCode = resp.StatusCode
+		}
+		session.accessLog.record(session.log, req, resp, body, attempt)
+
+		var retry, reauth bool
+		if challengeable && !challenged && err == nil && resp.StatusCode == http.StatusUnauthorized {
+			// answering an authentication challenge does not count against the retry policy
+			if challenged, err = challenger.Challenge(resp); challenged {
+				attempts++
+				retry, reauth = true, true
+			}
+			if err != nil {
+				if !buffered {
+					_ = resp.Body.Close()
+				}
+				attempt.Error = err
+				result.Attempts = append(result.Attempts, attempt)
+				break
+			}
+		}
+		if !retry && number < attempts {
+			if err != nil {
+				retry = session.retry.retryError(ctx, err)
+			} else {
+				retry = session.retry.retryStatus(resp.StatusCode)
+			}
+		}
+		if retry && !reauth {
+			attempt.Delay = session.retry.delay(number, resp)
+		}
+		result.Attempts = append(result.Attempts, attempt)
+
+		if !retry {
+			break
+		}
+		if !buffered && err == nil {
+			discardBody(resp.Body)
+		}
+		if err = sleepContext(ctx, attempt.Delay); err != nil {
+			break
+		}
 	}
-	defer resp.Body.Close()
-	result.Response = resp
 
-	// immediately reading the response ensures the body
-	// will be closed and the connection released
-	if body, err = io.ReadAll(resp.Body); err != nil {
+	result.Response = resp
+	result.Body = body
+	if err != nil {
 		result.Error = err
 		return &result
 	}
-	result.Body = body
 
 	for _, handler := range session.onResponse {
 		if err = handler(resp); err != nil {
+			if !buffered {
+				_ = resp.Body.Close()
+			}
 			result.Error = err
 			return &result
 		}
@@ -240,6 +388,120 @@ func (session *teacup) fetch(ctx context.Context) *Result {
 	return &result
 }
 
+// prepare creates the request and applies the request handlers and
+// headers of the Session to it.
+func (session *teacup) prepare(ctx context.Context) (*http.Request, error) {
+	var err error
+	var req *http.Request
+
+	if req, err = session.newRequest(ctx, session.location.String()); err != nil {
+		return nil, err
+	}
+
+	for _, handler := range session.onRequest {
+		if err = handler(req); err != nil {
+			return req, err
+		}
+	}
+
+	// TODO: should this be done here or before applying onRequest?
+	CopyHeaders(req.Header, session.headers, false)
+	if session.encoder != nil {
+		req.Header.Set("Content-Type", session.encoder.ContentType())
+	}
+	return req, nil
+}
+
+// newRequest creates the request with either the raw body or the encoder,
+// which also allows the body to be replayed without buffering it.
+func (session *teacup) newRequest(ctx context.Context, loc string) (*http.Request, error) {
+	var method = strings.ToUpper(session.method)
+	if session.encoder == nil {
+		return http.NewRequestWithContext(ctx, method, loc, session.body)
+	}
+
+	var err error
+	var req *http.Request
+	var body io.ReadCloser
+	if body, err = session.encoder.Open(); err != nil {
+		return nil, err
+	}
+	if req, err = http.NewRequestWithContext(ctx, method, loc, body); err != nil {
+		_ = body.Close()
+		return nil, err
+	}
+	req.GetBody = session.encoder.Open
+	if length := session.encoder.ContentLength(); length > 0 {
+		req.ContentLength = length
+	} else if length == 0 {
+		_ = body.Close()
+		req.Body = http.NoBody
+		req.GetBody = func() (io.ReadCloser, error) { return http.NoBody, nil }
+	}
+	return req, nil
+}
+
+// attempt performs a single round trip and, if buffered, reads the whole
+// response body. The body is always closed when an error is returned.
+func (session *teacup) attempt(client *http.Client, req *http.Request, buffered bool, timing *Timing) (*http.Response, []byte, error) {
+	var err error
+	var resp *http.Response
+	var body []byte
+
+	if timing != nil {
+		req = timing.trace(req)
+		defer func() { timing.done(buffered && body != nil) }()
+	}
+
+	var transport http.RoundTripper = RoundTripFunc(client.Do)
+	if len(session.middlewares) != 0 {
+		transport = buildChain(session.middlewares, transport)
+	}
+	if resp, err = transport.RoundTrip(req); err != nil {
+		return nil, nil, err
+	}
+	if session.maxBody > 0 && resp.ContentLength > session.maxBody {
+		_ = resp.Body.Close()
+		return resp, nil, &BodyTooLargeError{Limit: session.maxBody, Size: resp.ContentLength}
+	}
+	if !buffered {
+		return resp, nil, nil
+	}
+	defer resp.Body.Close()
+
+	// immediately reading the response ensures the body
+	// will be closed and the connection released
+	if body, err = readBody(resp.Body, session.maxBody); err != nil {
+		return resp, nil, err
+	}
+
+	return resp, body, nil
+}
+
+// readBody reads everything from r, failing once more than limit bytes
+// have been read; a limit of zero or less reads without a limit.
+func readBody(r io.Reader, limit int64) ([]byte, error) {
+	if limit <= 0 {
+		return io.ReadAll(r)
+	}
+
+	var body, err = io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return body, err
+	}
+	if int64(len(body)) > limit {
+		return body[:limit], &BodyTooLargeError{Limit: limit}
+	}
+	return body, nil
+}
+
+// discardBody drains and closes a body so that the connection can be reused.
+func discardBody(body io.ReadCloser) {
+	// a cap avoids downloading an arbitrarily large error page just to reuse the connection
+	_, _ = io.Copy(io.Discard, io.LimitReader(body, 1<<16))
+	_ = body.Close()
+}
+
 // TODO: should gzip be handled manually?
 // TODO: https://stackoverflow.com/questions/71011274/golang-default-http-client-doesnt-handle-compression
 // var body *strings.Reader
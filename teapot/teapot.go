@@ -16,6 +16,7 @@ type Teapot interface {
 	Session() Session
 	OnRequest() []RequestInterceptor
 	OnResponse() []ResponseInterceptor
+	Middlewares() []Middleware
 
 	Client() *http.Client
 	// Jar() http.CookieJar
@@ -25,22 +26,47 @@ type Teapot interface {
 //
 // TODO: initialization from viper instance instead of config?
 type teapot struct {
-	log        logging.Logger
-	config     *Config
-	transport  *http.Transport
-	tlsconfig  *tls.Config
-	headers    http.Header
-	httpclient *http.Client
-	cookiejar  http.CookieJar
-	jarLoaders []cookiejar.Loader
-	onRequest  []RequestInterceptor
-	onResponse []ResponseInterceptor
+	log           logging.Logger
+	config        *Config
+	retry         *RetryConfig
+	accessLog     *AccessLogConfig
+	limits        []*HostLimit
+	limiter       *hostLimiter
+	proxyConfig   *ProxyConfig
+	proxies       *proxySelector
+	breakerConfig *CircuitBreakerConfig
+	breaker       *circuitBreaker
+	cache         CacheStore
+	auth          Authenticator
+	transport     *http.Transport
+	wrappers      []TransportWrapper
+	tlsconfig     *tls.Config
+	headers       http.Header
+	httpclient    *http.Client
+	cookiejar     http.CookieJar
+	jarLoaders    []cookiejar.Loader
+	onRequest     []RequestInterceptor
+	onResponse    []ResponseInterceptor
+	middlewares   []Middleware
 }
 
 func (tpt *teapot) clone() *teapot {
 	var clone = &teapot{
-		log:    tpt.log,
-		config: tpt.config,
+		log:       tpt.log,
+		config:    tpt.config,
+		retry:     tpt.retry,
+		accessLog: tpt.accessLog,
+		limits:    tpt.limits,
+		// the limiter is shared so that clones are paced together
+		limiter: tpt.hostLimiter(),
+		// as is the health of the proxy pool
+		proxyConfig: tpt.proxyConfig,
+		proxies:     tpt.proxySelector(),
+		// and the circuits of every host
+		breakerConfig: tpt.breakerConfig,
+		breaker:       tpt.circuitBreaker(),
+		cache:         tpt.cache,
+		auth:          tpt.auth,
 		transport: func() *http.Transport {
 			if tpt.transport != nil {
 				return tpt.transport.Clone()
@@ -53,12 +79,14 @@ func (tpt *teapot) clone() *teapot {
 			}
 			return nil
 		}(),
-		headers:    tpt.headers.Clone(),
-		httpclient: tpt.httpclient,
-		cookiejar:  tpt.cookiejar,
-		jarLoaders: append(make([]cookiejar.Loader, 0, len(tpt.jarLoaders)), tpt.jarLoaders...),
-		onRequest:  append(make([]RequestInterceptor, 0, len(tpt.onRequest)), tpt.onRequest...),
-		onResponse: append(make([]ResponseInterceptor, 0, len(tpt.onResponse)), tpt.onResponse...),
+		wrappers:    append(make([]TransportWrapper, 0, len(tpt.wrappers)), tpt.wrappers...),
+		headers:     tpt.headers.Clone(),
+		httpclient:  tpt.httpclient,
+		cookiejar:   tpt.cookiejar,
+		jarLoaders:  append(make([]cookiejar.Loader, 0, len(tpt.jarLoaders)), tpt.jarLoaders...),
+		onRequest:   append(make([]RequestInterceptor, 0, len(tpt.onRequest)), tpt.onRequest...),
+		onResponse:  append(make([]ResponseInterceptor, 0, len(tpt.onResponse)), tpt.onResponse...),
+		middlewares: append(make([]Middleware, 0, len(tpt.middlewares)), tpt.middlewares...),
 	}
 
 	return clone
@@ -77,6 +105,10 @@ func (tpt *teapot) Session() Session {
 	cup.client = tpt.Client()
 	cup.jar = tpt.cookiejar
 	cup.headers = tpt.headers.Clone()
+	cup.log = tpt.log
+	cup.retry = tpt.retryConfig()
+	cup.accessLog = tpt.accessLogConfig()
+	cup.auth = tpt.auth
 	if cup.jar == nil && cup.client.Jar != nil {
 		cup.jar = cup.client.Jar
 	} else if cup.jar == nil && cup.client.Jar == nil {
@@ -90,6 +122,7 @@ func (tpt *teapot) Session() Session {
 	if tpt.onResponse != nil {
 		cup.onResponse = tpt.onResponse[:]
 	}
+	cup.middlewares = append(make([]Middleware, 0, len(tpt.middlewares)), tpt.middlewares...)
 	return cup
 }
 
@@ -101,9 +134,85 @@ func (tpt *teapot) OnResponse() []ResponseInterceptor {
 	return tpt.onResponse
 }
 
-func (tpt *teapot) Client() *http.Client {
-	// TODO: teapot must support retries!!! and be configurable for statuses that should be retried and how many times
+func (tpt *teapot) Middlewares() []Middleware {
+	return tpt.middlewares
+}
+
+// accessLogConfig prefers a configuration provided with UseAccessLog over the Config.
+func (tpt *teapot) accessLogConfig() *AccessLogConfig {
+	if tpt.accessLog != nil {
+		return tpt.accessLog
+	}
+	if tpt.config != nil {
+		return tpt.config.AccessLog
+	}
+	return nil
+}
+
+// retryConfig prefers a policy provided with UseRetry over the one in the Config.
+func (tpt *teapot) retryConfig() *RetryConfig {
+	if tpt.retry != nil {
+		return tpt.retry
+	}
+	if tpt.config != nil {
+		return tpt.config.Retry
+	}
+	return nil
+}
+
+// hostLimiter lazily creates the per-host limits from UseRateLimits or the Config.
+func (tpt *teapot) hostLimiter() *hostLimiter {
+	if tpt.limiter == nil {
+		var limits = tpt.limits
+		if limits == nil && tpt.config != nil {
+			limits = tpt.config.RateLimits
+		}
+		if len(limits) == 0 {
+			return nil
+		}
+		tpt.limiter = newHostLimiter(limits)
+	}
+	return tpt.limiter
+}
+
+func (tpt *teapot) proxySelector() *proxySelector {
+	if tpt.proxies == nil {
+		var cfg = tpt.proxyConfig
+		if cfg == nil && tpt.config != nil {
+			cfg = tpt.config.Proxy
+		}
+		if cfg == nil {
+			return nil
+		}
+		tpt.proxies = newProxySelector(cfg)
+	}
+	return tpt.proxies
+}
+
+func (tpt *teapot) circuitBreaker() *circuitBreaker {
+	if tpt.breaker == nil {
+		var cfg = tpt.breakerConfig
+		if cfg == nil && tpt.config != nil {
+			cfg = tpt.config.CircuitBreaker
+		}
+		if cfg == nil {
+			return nil
+		}
+		tpt.breaker = newCircuitBreaker(cfg, tpt.log)
+	}
+	return tpt.breaker
+}
 
+// resetClient drops the Client so that the next one is built with the
+// changed options; a jar it created is kept so the cookies carry over.
+func (tpt *teapot) resetClient() {
+	if tpt.httpclient != nil && tpt.cookiejar == nil {
+		tpt.cookiejar = tpt.httpclient.Jar
+	}
+	tpt.httpclient = nil
+}
+
+func (tpt *teapot) Client() *http.Client {
 	if tpt.httpclient == nil {
 		if tpt.config == nil {
 			tpt.config = &Config{Timeout: time.Second * 10}
@@ -132,6 +241,9 @@ func (tpt *teapot) Client() *http.Client {
 		// 	MaxConnsPerHost:       5,
 		// })
 
+		// a broken TLS setup fails every request rather than silently
+		// falling back to a weaker configuration
+		var tlsErr error
 		if tpt.tlsconfig == nil {
 			tpt.tlsconfig = &tls.Config{
 				// NOTE: `gosec` linter complains if this is not explicitly
@@ -142,7 +254,7 @@ func (tpt *teapot) Client() *http.Client {
 				InsecureSkipVerify: false,
 			}
 			if tpt.config != nil && tpt.config.TLS != nil {
-				tpt.config.TLS.Apply(tpt.tlsconfig)
+				tlsErr = tpt.config.TLS.Configure(tpt.tlsconfig)
 			}
 
 			// TODO
@@ -172,7 +284,26 @@ func (tpt *teapot) Client() *http.Client {
 			}
 		}
 
-		tpt.httpclient = &http.Client{Transport: tpt.transport, Timeout: tpt.config.Timeout, Jar: jar}
+		var proxies = tpt.proxySelector()
+		if proxies != nil {
+			tpt.transport.Proxy = proxies.proxy
+		}
+
+		var transport http.RoundTripper = proxies.wrap(tpt.transport)
+		if tlsErr != nil {
+			transport = &failedTransport{err: tlsErr}
+		}
+		for _, wrap := range tpt.wrappers {
+			transport = wrap(transport)
+		}
+
+		// cache hits are answered before any rate limits are applied and an
+		// open circuit fails before waiting for them
+		transport = tpt.hostLimiter().wrap(transport)
+		transport = tpt.circuitBreaker().wrap(transport)
+		transport = newCacheTransport(tpt.cache, transport)
+
+		tpt.httpclient = &http.Client{Transport: transport, Timeout: tpt.config.Timeout, Jar: jar}
 	}
 
 	return tpt.httpclient
@@ -0,0 +1,40 @@
+package teapot
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestTeapot_MutateRebuildsClient(t *testing.T) {
+	var base = Builder().Config(&Config{Timeout: time.Second}).Make()
+	var client = base.Client()
+
+	var mutated = base.Mutate().
+		Config(&Config{Timeout: 5 * time.Second}).
+		RateLimits(&HostLimit{Host: "*", Rate: 1}).
+		Make()
+	if mutated.Client() == client {
+		t.Fatal("did not expect the Client to be shared after changing the Config")
+	}
+	if timeout := mutated.Client().Timeout; timeout != 5*time.Second {
+		t.Errorf("did not get expected timeout %s != %s", timeout, 5*time.Second)
+	}
+	if _, ok := mutated.Client().Transport.(*limitedTransport); !ok {
+		t.Errorf("did not get expected rate limited transport: %T", mutated.Client().Transport)
+	}
+	if mutated.Client().Jar != client.Jar {
+		t.Error("did not expect the cookie jar to change")
+	}
+	if base.Client() != client {
+		t.Error("did not expect the original Client to change")
+	}
+}
+
+func TestTeapot_CloneSharesClient(t *testing.T) {
+	var base = Builder().Config(&Config{Timeout: time.Second}).Make()
+	var client = base.Client()
+	if clone := base.Mutate().AddHeaders(http.Header{"X-Test": {"1"}}).Make(); clone.Client() != client {
+		t.Error("did not expect the Client to change along with the headers")
+	}
+}
@@ -0,0 +1,70 @@
+package teapottest
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// Status responds with the status code and an empty body.
+func Status(status int) Response {
+	return Response{Status: status}
+}
+
+// Text responds with the status code and a plain text body.
+func Text(status int, body string) Response {
+	return Response{
+		Status: status,
+		Header: http.Header{"Content-Type": []string{"text/plain; charset=utf-8"}},
+		Body:   []byte(body),
+	}
+}
+
+// JSON responds with the status code and v marshalled as the body;
+// it panics if v cannot be marshalled since that is a bug in the test.
+func JSON(status int, v any) Response {
+	var body, err = json.Marshal(v)
+	if err != nil {
+		panic("teapottest: unable to marshal JSON response: " + err.Error())
+	}
+	return Response{
+		Status: status,
+		Header: http.Header{"Content-Type": []string{"application/json"}},
+		Body:   body,
+	}
+}
+
+// Times repeats a response n times, e.g. to build a sequence that
+// fails a number of times before it succeeds:
+//
+//	append(teapottest.Times(2, teapottest.Status(503)), teapottest.Text(200, "ok"))
+func Times(n int, resp Response) []Response {
+	var responses = make([]Response, 0, n)
+	for i := 0; i < n; i++ {
+		responses = append(responses, resp)
+	}
+	return responses
+}
+
+// WithHeader returns a copy of the response with a header added.
+func (resp Response) WithHeader(name string, value string) Response {
+	var header = resp.Header.Clone()
+	if header == nil {
+		header = make(http.Header)
+	}
+	header.Add(name, value)
+	resp.Header = header
+	return resp
+}
+
+// WithCookie returns a copy of the response that also sets a cookie.
+func (resp Response) WithCookie(cookie *http.Cookie) Response {
+	resp.Cookies = append(append(make([]*http.Cookie, 0, len(resp.Cookies)+1), resp.Cookies...), cookie)
+	return resp
+}
+
+// After returns a copy of the response that is delayed.
+func (resp Response) After(delay time.Duration) Response {
+	resp.Delay = delay
+	return resp
+}
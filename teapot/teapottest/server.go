@@ -0,0 +1,265 @@
+// Package teapottest provides an HTTP test server with a declarative route
+// table for unit testing code built on teapot without network access.
+package teapottest
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gadget/teapot"
+)
+
+// Route describes how the server answers requests for a method and path.
+//
+// Responses are served in order, one per request, and the last one is
+// repeated once the sequence is exhausted; a route that should fail twice
+// and then succeed therefore lists two failures followed by a success.
+// When Handler is set it is used instead of Responses.
+type Route struct {
+	// Method restricts the route to one HTTP method; empty matches any.
+	Method string
+
+	// Path is matched exactly unless it ends in `*`, which matches any
+	// path with that prefix.
+	Path string
+
+	Responses []Response
+	Handler   http.HandlerFunc
+}
+
+func (route *Route) matches(req *http.Request) bool {
+	if route.Method != "" && !strings.EqualFold(route.Method, req.Method) {
+		return false
+	}
+	if strings.HasSuffix(route.Path, "*") {
+		return strings.HasPrefix(req.URL.Path, strings.TrimSuffix(route.Path, "*"))
+	}
+	return route.Path == req.URL.Path
+}
+
+// Response is a canned answer to a request.
+type Response struct {
+	Status  int
+	Header  http.Header
+	Cookies []*http.Cookie
+	Body    []byte
+
+	// Delay is waited before the response is written; the wait ends early
+	// if the client gives up on the request.
+	Delay time.Duration
+}
+
+func (resp *Response) write(w http.ResponseWriter, req *http.Request) {
+	if resp.Delay > 0 {
+		var timer = time.NewTimer(resp.Delay)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-req.Context().Done():
+			return
+		}
+	}
+
+	for name, values := range resp.Header {
+		for _, value := range values {
+			w.Header().Add(name, value)
+		}
+	}
+	for _, cookie := range resp.Cookies {
+		http.SetCookie(w, cookie)
+	}
+
+	var status = resp.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	w.WriteHeader(status)
+	_, _ = w.Write(resp.Body)
+}
+
+// Request is a copy of a request received by the server.
+type Request struct {
+	Method  string
+	URL     *url.URL
+	Header  http.Header
+	Cookies []*http.Cookie
+	Body    []byte
+	Matched bool
+	At      time.Time
+}
+
+// Server is an httptest.Server answering from a route table and
+// recording every request it receives.
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	routes   []*Route
+	served   map[*Route]int
+	requests []*Request
+}
+
+// NewServer starts a TLS server for the routes that is closed when the test ends.
+func NewServer(tb testing.TB, routes ...Route) *Server {
+	tb.Helper()
+
+	var srv = newServer(routes)
+	srv.Server = httptest.NewTLSServer(http.HandlerFunc(srv.serve))
+	tb.Cleanup(srv.Close)
+	return srv
+}
+
+// NewPlainServer starts a server without TLS that is closed when the test ends.
+func NewPlainServer(tb testing.TB, routes ...Route) *Server {
+	tb.Helper()
+
+	var srv = newServer(routes)
+	srv.Server = httptest.NewServer(http.HandlerFunc(srv.serve))
+	tb.Cleanup(srv.Close)
+	return srv
+}
+
+func newServer(routes []Route) *Server {
+	var srv = &Server{served: make(map[*Route]int)}
+	for index := range routes {
+		srv.routes = append(srv.routes, &routes[index])
+	}
+	return srv
+}
+
+// Handle adds a route; routes added later take precedence.
+func (srv *Server) Handle(route Route) {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	srv.routes = append([]*Route{&route}, srv.routes...)
+}
+
+// Location returns the absolute URL of path on the server.
+func (srv *Server) Location(path string) string {
+	return srv.URL + path
+}
+
+// Builder returns a teapot Constructor whose transport trusts the
+// server's certificate, to be customized further by the test.
+func (srv *Server) Builder() teapot.Constructor {
+	var transport = srv.Client().Transport.(*http.Transport).Clone()
+	return teapot.Builder().Transport(transport)
+}
+
+// Teapot returns a Teapot whose transport trusts the server's certificate.
+func (srv *Server) Teapot() teapot.Teapot {
+	return srv.Builder().New()
+}
+
+// Requests returns every request received so far, in order.
+func (srv *Server) Requests() []*Request {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	return append(make([]*Request, 0, len(srv.requests)), srv.requests...)
+}
+
+// RequestsTo returns the requests received for a method and path;
+// an empty method matches any.
+func (srv *Server) RequestsTo(method string, path string) []*Request {
+	var matched []*Request
+	for _, req := range srv.Requests() {
+		if (method == "" || strings.EqualFold(method, req.Method)) && req.URL.Path == path {
+			matched = append(matched, req)
+		}
+	}
+	return matched
+}
+
+// Unmatched returns the requests that no route answered.
+func (srv *Server) Unmatched() []*Request {
+	var unmatched []*Request
+	for _, req := range srv.Requests() {
+		if !req.Matched {
+			unmatched = append(unmatched, req)
+		}
+	}
+	return unmatched
+}
+
+// ExpectRequests fails the test unless exactly count requests were
+// received for the method and path.
+func (srv *Server) ExpectRequests(tb testing.TB, method string, path string, count int) []*Request {
+	tb.Helper()
+
+	var matched = srv.RequestsTo(method, path)
+	if len(matched) != count {
+		tb.Errorf("expected %d requests for %s %s but received %d", count, method, path, len(matched))
+	}
+	return matched
+}
+
+// ExpectNoUnmatched fails the test if any request was not answered by a route.
+func (srv *Server) ExpectNoUnmatched(tb testing.TB) {
+	tb.Helper()
+
+	for _, req := range srv.Unmatched() {
+		tb.Errorf("unexpected request: %s %s", req.Method, req.URL.String())
+	}
+}
+
+func (srv *Server) serve(w http.ResponseWriter, req *http.Request) {
+	var body, _ = io.ReadAll(req.Body)
+	req.Body = io.NopCloser(bytes.NewReader(body))
+
+	var record = &Request{
+		Method:  req.Method,
+		URL:     req.URL,
+		Header:  req.Header.Clone(),
+		Cookies: req.Cookies(),
+		Body:    body,
+		At:      time.Now(),
+	}
+
+	var route, response = srv.route(req)
+	record.Matched = route != nil
+
+	srv.mu.Lock()
+	srv.requests = append(srv.requests, record)
+	srv.mu.Unlock()
+
+	switch {
+	case route == nil:
+		http.Error(w, "no route for "+req.Method+" "+req.URL.Path, http.StatusNotFound)
+	case route.Handler != nil:
+		route.Handler(w, req)
+	case response != nil:
+		response.write(w, req)
+	default:
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// route finds the route for the request and advances its response sequence.
+func (srv *Server) route(req *http.Request) (*Route, *Response) {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
+	for _, route := range srv.routes {
+		if !route.matches(req) {
+			continue
+		}
+		if len(route.Responses) == 0 {
+			return route, nil
+		}
+
+		var index = srv.served[route]
+		srv.served[route] = index + 1
+		if index >= len(route.Responses) {
+			index = len(route.Responses) - 1
+		}
+		return route, &route.Responses[index]
+	}
+	return nil, nil
+}
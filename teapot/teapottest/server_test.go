@@ -0,0 +1,34 @@
+package teapottest
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"gadget/teapot"
+)
+
+func TestServer_SequenceWithRetries(t *testing.T) {
+	var srv = NewServer(t, Route{
+		Method:    http.MethodGet,
+		Path:      "/flaky",
+		Responses: append(Times(2, Status(http.StatusServiceUnavailable)), Text(http.StatusOK, "ok").WithCookie(&http.Cookie{Name: "session", Value: "abc"})),
+	})
+
+	var session = srv.Builder().Retry(&teapot.RetryConfig{MaxAttempts: 3, BaseDelay: time.Millisecond}).New().Session()
+	var result = session.URLstring(srv.Location("/flaky")).Get(context.Background())
+	if result.Error != nil {
+		t.Fatalf("unexpected error: %v", result.Error)
+	}
+	if result.Tries() != 3 || result.Text() != "ok" {
+		t.Errorf("did not get expected result: tries=%d body=%s", result.Tries(), result.Text())
+	}
+
+	_ = session.URLstring(srv.Location("/flaky")).Get(context.Background())
+	var requests = srv.ExpectRequests(t, http.MethodGet, "/flaky", 4)
+	if len(requests) == 4 && (len(requests[3].Cookies) != 1 || requests[3].Cookies[0].Value != "abc") {
+		t.Errorf("expected session cookie to be sent: %+v", requests[3].Cookies)
+	}
+	srv.ExpectNoUnmatched(t)
+}
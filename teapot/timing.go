@@ -0,0 +1,191 @@
+package teapot
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"net/http/httptrace"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Timing breaks down where the time of a single attempt was spent, as
+// captured with net/http/httptrace; see Requestor.Trace.
+//
+// When redirects are followed, the phases describe the final request
+// while Total covers the whole attempt.
+type Timing struct {
+	mu sync.Mutex
+
+	Start time.Time
+
+	// DNS is zero when the host was an IP address or the connection reused.
+	DNS time.Duration
+
+	// Connect is the time taken to establish the TCP connection.
+	Connect time.Duration
+
+	// TLSHandshake is zero for plain HTTP or a reused connection.
+	TLSHandshake time.Duration
+
+	// TimeToFirstByte is the time from the start of the attempt until
+	// the first byte of the response headers arrived.
+	TimeToFirstByte time.Duration
+
+	// Server is the time between writing the request and the first byte
+	// of the response, which is mostly spent by the server.
+	Server time.Duration
+
+	// BodyRead is the time taken to read a buffered response body.
+	BodyRead time.Duration
+
+	// Total is the duration of the whole attempt.
+	Total time.Duration
+
+	// Reused is true if an existing keep-alive connection was used.
+	Reused     bool
+	WasIdle    bool
+	IdleTime   time.Duration
+	RemoteAddr string
+
+	dnsStart     time.Time
+	connectStart time.Time
+	tlsStart     time.Time
+	wrote        time.Time
+	firstByte    time.Time
+}
+
+// trace attaches the hooks that fill in the Timing to the request.
+func (timing *Timing) trace(req *http.Request) *http.Request {
+	timing.Start = time.Now()
+	var trace = &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			timing.mu.Lock()
+			defer timing.mu.Unlock()
+			timing.dnsStart = time.Now()
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			timing.mu.Lock()
+			defer timing.mu.Unlock()
+			timing.DNS = time.Since(timing.dnsStart)
+		},
+		ConnectStart: func(string, string) {
+			timing.mu.Lock()
+			defer timing.mu.Unlock()
+			// several addresses may be dialed at once; the first start counts
+			if timing.connectStart.IsZero() {
+				timing.connectStart = time.Now()
+			}
+		},
+		ConnectDone: func(_ string, _ string, err error) {
+			timing.mu.Lock()
+			defer timing.mu.Unlock()
+			if err == nil {
+				timing.Connect = time.Since(timing.connectStart)
+			}
+		},
+		TLSHandshakeStart: func() {
+			timing.mu.Lock()
+			defer timing.mu.Unlock()
+			timing.tlsStart = time.Now()
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			timing.mu.Lock()
+			defer timing.mu.Unlock()
+			timing.TLSHandshake = time.Since(timing.tlsStart)
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			timing.mu.Lock()
+			defer timing.mu.Unlock()
+			timing.Reused, timing.WasIdle, timing.IdleTime = info.Reused, info.WasIdle, info.IdleTime
+			if info.Conn != nil {
+				timing.RemoteAddr = info.Conn.RemoteAddr().String()
+			}
+			if info.Reused {
+				// a redirect on a reused connection has no new phases
+				timing.DNS, timing.Connect, timing.TLSHandshake = 0, 0, 0
+			}
+			timing.connectStart = time.Time{}
+		},
+		WroteRequest: func(httptrace.WroteRequestInfo) {
+			timing.mu.Lock()
+			defer timing.mu.Unlock()
+			timing.wrote = time.Now()
+		},
+		GotFirstResponseByte: func() {
+			timing.mu.Lock()
+			defer timing.mu.Unlock()
+			timing.firstByte = time.Now()
+			timing.TimeToFirstByte = timing.firstByte.Sub(timing.Start)
+			if !timing.wrote.IsZero() {
+				timing.Server = timing.firstByte.Sub(timing.wrote)
+			}
+		},
+	}
+	return req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+}
+
+// done records the end of the attempt once the body has been read, if at all.
+func (timing *Timing) done(bodyRead bool) {
+	timing.mu.Lock()
+	defer timing.mu.Unlock()
+	var now = time.Now()
+	if bodyRead && !timing.firstByte.IsZero() {
+		timing.BodyRead = now.Sub(timing.firstByte)
+	}
+	timing.Total = now.Sub(timing.Start)
+}
+
+// String formats the breakdown as a table, e.g. for debugging output.
+func (timing *Timing) String() string {
+	if timing == nil {
+		return "no timing captured"
+	}
+	timing.mu.Lock()
+	defer timing.mu.Unlock()
+
+	var dump strings.Builder
+	var row = func(name string, value any) {
+		dump.WriteString(fmt.Sprintf("%-20s %v\n", name+":", value))
+	}
+	row("DNS lookup", timing.DNS)
+	row("TCP connection", timing.Connect)
+	row("TLS handshake", timing.TLSHandshake)
+	row("Server processing", timing.Server)
+	row("Time to first byte", timing.TimeToFirstByte)
+	row("Content transfer", timing.BodyRead)
+	row("Total", timing.Total)
+	if timing.Reused {
+		row("Connection", fmt.Sprintf("reused (idle %v)", timing.IdleTime))
+	} else {
+		row("Connection", "new")
+	}
+	if timing.RemoteAddr != "" {
+		row("Remote address", timing.RemoteAddr)
+	}
+	return dump.String()
+}
+
+// Timing returns the breakdown of the final attempt or nil if the
+// request was not traced.
+func (res *Result) Timing() *Timing {
+	if res == nil || len(res.Attempts) == 0 {
+		return nil
+	}
+	return res.Attempts[len(res.Attempts)-1].Timing
+}
+
+// DumpTiming returns the timing breakdown of every attempt in a readable
+// form similar to Dump.
+func (res *Result) DumpTiming() string {
+	var dump strings.Builder
+	for _, attempt := range res.Attempts {
+		dump.WriteString(fmt.Sprintf("\nATTEMPT %d", attempt.Number))
+		if attempt.StatusCode != 0 {
+			dump.WriteString(fmt.Sprintf(" (%d)", attempt.StatusCode))
+		}
+		dump.WriteString("\n" + attempt.Timing.String())
+	}
+	return dump.String()
+}
@@ -0,0 +1,224 @@
+package teapot
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PublicKeyPin accepts only servers with one of the given public keys
+// somewhere in their verified certificate chain.
+//
+// Host is either an exact host name or a pattern in the syntax of
+// path.Match, e.g. `*.example.com`; every matching pin must be satisfied.
+type PublicKeyPin struct {
+	Host string `mapstructure:"host" json:"host"`
+
+	// SHA256 are base64 encoded SHA-256 hashes of the DER encoded
+	// SubjectPublicKeyInfo; a `sha256/` prefix as in HPKP is allowed.
+	SHA256 []string `mapstructure:"sha256" json:"sha256"`
+}
+
+func (pin *PublicKeyPin) matches(host string) bool {
+	if strings.EqualFold(pin.Host, host) {
+		return true
+	}
+	var matched, err = path.Match(strings.ToLower(pin.Host), strings.ToLower(host))
+	return err == nil && matched
+}
+
+// SPKIHash returns the pin of a certificate's public key as used by PublicKeyPin.
+func SPKIHash(cert *x509.Certificate) string {
+	var sum = sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+type pinSet struct {
+	pins   []*PublicKeyPin
+	hashes []map[string]bool
+}
+
+func newPinSet(pins []*PublicKeyPin) (*pinSet, error) {
+	var set = &pinSet{}
+	for _, pin := range pins {
+		if pin == nil {
+			continue
+		}
+		var hashes = make(map[string]bool, len(pin.SHA256))
+		for _, hash := range pin.SHA256 {
+			hash = strings.TrimPrefix(strings.TrimSpace(hash), "sha256/")
+			if raw, err := base64.StdEncoding.DecodeString(hash); err != nil || len(raw) != sha256.Size {
+				return nil, &TLSConfigError{Reason: "invalid SHA-256 pin '" + hash + "' for '" + pin.Host + "'"}
+			}
+			hashes[hash] = true
+		}
+		set.pins = append(set.pins, pin)
+		set.hashes = append(set.hashes, hashes)
+	}
+	return set, nil
+}
+
+// verifier returns the VerifyConnection callback, which runs after the
+// regular verification of the chain.
+//
+// Only the verified chains count: the server may send any certificate
+// along with its own, including the pinned one, so PeerCertificates proves
+// nothing. Without verification only the leaf certificate is checked.
+func (set *pinSet) verifier(config *tls.Config) func(state tls.ConnectionState) error {
+	return func(state tls.ConnectionState) error {
+		var chains = state.VerifiedChains
+		if config.InsecureSkipVerify && len(state.PeerCertificates) != 0 {
+			chains = [][]*x509.Certificate{state.PeerCertificates[:1]}
+		}
+		var hosts = []string{state.ServerName}
+		if state.ServerName == "" && len(chains) != 0 && len(chains[0]) != 0 {
+			// no server name is sent for IP addresses, which the leaf was
+			// verified against instead
+			hosts = hosts[:0]
+			for _, ip := range chains[0][0].IPAddresses {
+				hosts = append(hosts, ip.String())
+			}
+		}
+		for index, pin := range set.pins {
+			for _, host := range hosts {
+				if pin.matches(host) && !set.pinned(index, chains) {
+					return &PublicKeyPinError{Host: host}
+				}
+			}
+		}
+		return nil
+	}
+}
+
+func (set *pinSet) pinned(index int, chains [][]*x509.Certificate) bool {
+	for _, chain := range chains {
+		for _, cert := range chain {
+			if set.hashes[index][SPKIHash(cert)] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func loadCertPool(paths []string, replace bool) (*x509.CertPool, error) {
+	var pool *x509.CertPool
+	if !replace {
+		pool, _ = x509.SystemCertPool()
+	}
+	if pool == nil {
+		pool = x509.NewCertPool()
+	}
+	for _, path := range paths {
+		var bundle, err = os.ReadFile(filepath.Clean(path))
+		if err != nil {
+			return nil, &TLSConfigError{Path: path, Reason: err.Error()}
+		}
+		if !pool.AppendCertsFromPEM(bundle) {
+			return nil, &TLSConfigError{Path: path, Reason: "no PEM certificates found"}
+		}
+	}
+	return pool, nil
+}
+
+// certReloader loads a client certificate again whenever one of its
+// files has been modified since it was last loaded.
+type certReloader struct {
+	certPath string
+	keyPath  string
+
+	mu       sync.Mutex
+	cert     *tls.Certificate
+	modified time.Time
+}
+
+func newCertReloader(certPath string, keyPath string) (*certReloader, error) {
+	if certPath == "" || keyPath == "" {
+		return nil, &TLSConfigError{Reason: "client certificate requires both a certificate and a key path"}
+	}
+	var reloader = &certReloader{certPath: certPath, keyPath: keyPath}
+	if _, err := reloader.certificate(); err != nil {
+		return nil, err
+	}
+	return reloader, nil
+}
+
+func (reloader *certReloader) lastModified() (time.Time, error) {
+	var latest time.Time
+	for _, path := range []string{reloader.certPath, reloader.keyPath} {
+		var info, err = os.Stat(path)
+		if err != nil {
+			return latest, &TLSConfigError{Path: path, Reason: err.Error()}
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
+
+func (reloader *certReloader) certificate() (*tls.Certificate, error) {
+	reloader.mu.Lock()
+	defer reloader.mu.Unlock()
+
+	var modified, err = reloader.lastModified()
+	if err != nil || !modified.After(reloader.modified) {
+		// a certificate being replaced on disk keeps the previous one in use
+		if reloader.cert != nil {
+			return reloader.cert, nil
+		}
+		return nil, err
+	}
+
+	var cert tls.Certificate
+	if cert, err = tls.LoadX509KeyPair(reloader.certPath, reloader.keyPath); err != nil {
+		if reloader.cert != nil {
+			return reloader.cert, nil
+		}
+		return nil, &TLSConfigError{Path: reloader.certPath, Reason: err.Error()}
+	}
+	reloader.cert, reloader.modified = &cert, modified
+	return reloader.cert, nil
+}
+
+func (reloader *certReloader) clientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	return reloader.certificate()
+}
+
+func cipherSuites(names []string) ([]uint16, error) {
+	var known = make(map[string]uint16)
+	for _, suite := range append(tls.CipherSuites(), tls.InsecureCipherSuites()...) {
+		known[suite.Name] = suite.ID
+	}
+
+	var ids = make([]uint16, 0, len(names))
+	for _, name := range names {
+		var id, found = known[strings.ToUpper(strings.TrimSpace(name))]
+		if !found {
+			return nil, &TLSConfigError{Reason: "unknown cipher suite '" + name + "'"}
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// failedTransport fails every request with an error that prevented the
+// transport from being set up, e.g. a missing certificate file.
+type failedTransport struct {
+	err error
+}
+
+func (ft *failedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		_ = req.Body.Close()
+	}
+	return nil, ft.err
+}
@@ -0,0 +1,118 @@
+package teapot
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCert(t *testing.T, name string, parent *testCert, leaf bool) *testCert {
+	t.Helper()
+	var key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var template = &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		BasicConstraintsValid: true,
+		IsCA:                  !leaf,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+	}
+	if leaf {
+		template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+		template.IPAddresses = []net.IP{net.ParseIP("127.0.0.1")}
+	}
+	var signer, signerKey = template, key
+	if parent != nil {
+		signer, signerKey = parent.cert, parent.key
+	}
+	var der []byte
+	if der, err = x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey); err != nil {
+		t.Fatal(err)
+	}
+	var cert *x509.Certificate
+	if cert, err = x509.ParseCertificate(der); err != nil {
+		t.Fatal(err)
+	}
+	return &testCert{cert: cert, key: key}
+}
+
+// pinnedServer serves the chain and returns its URL with a CA file that trusts root.
+func pinnedServer(t *testing.T, root *testCert, chain ...*testCert) (string, string) {
+	t.Helper()
+	var certificate = tls.Certificate{PrivateKey: chain[0].key, Leaf: chain[0].cert}
+	for _, cert := range chain {
+		certificate.Certificate = append(certificate.Certificate, cert.cert.Raw)
+	}
+	var server = httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.TLS = &tls.Config{Certificates: []tls.Certificate{certificate}}
+	server.Config.ErrorLog = log.New(io.Discard, "", 0)
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	var caPath = filepath.Join(t.TempDir(), "ca.pem")
+	var bundle = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: root.cert.Raw})
+	if err := os.WriteFile(caPath, bundle, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return server.URL, caPath
+}
+
+func TestPublicKeyPin_IgnoresUnverifiedCertificates(t *testing.T) {
+	var root = newTestCert(t, "trusted root", nil, false)
+	var leaf = newTestCert(t, "attacker", root, true)
+	var pinned = newTestCert(t, "pinned", nil, false)
+
+	// the pinned certificate is sent along but is not part of the verified chain
+	var location, caPath = pinnedServer(t, root, leaf, pinned)
+	var cfg = &Config{TLS: &TLSConfig{
+		CAPaths: []string{caPath},
+		Pins:    []*PublicKeyPin{{Host: "127.0.0.1", SHA256: []string{SPKIHash(pinned.cert)}}},
+	}}
+	var result = cfg.Builder().New().Session().URLstring(location).Get(context.Background())
+
+	var pinErr *PublicKeyPinError
+	if !errors.As(result.Error, &pinErr) {
+		t.Fatalf("did not get expected pin error: %v", result.Error)
+	}
+}
+
+func TestPublicKeyPin_AcceptsVerifiedChain(t *testing.T) {
+	var root = newTestCert(t, "trusted root", nil, false)
+	var leaf = newTestCert(t, "server", root, true)
+
+	var location, caPath = pinnedServer(t, root, leaf)
+	var cfg = &Config{TLS: &TLSConfig{
+		CAPaths: []string{caPath},
+		Pins:    []*PublicKeyPin{{Host: "127.0.0.1", SHA256: []string{SPKIHash(root.cert)}}},
+	}}
+	var result = cfg.Builder().New().Session().URLstring(location).Get(context.Background())
+
+	if result.Error != nil || result.StatusCode() != http.StatusOK {
+		t.Fatalf("did not get expected response: status=%d error=%v", result.StatusCode(), result.Error)
+	}
+}